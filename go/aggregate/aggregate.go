@@ -0,0 +1,272 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregate
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/cap/go/atom"
+	"github.com/IBM/cap/go/cap"
+)
+
+// DefaultWindow is the number of alerts an Aggregator remembers to detect
+// duplicates when Window is zero
+const DefaultWindow int = 10000
+
+// Source - a feed polled by an Aggregator
+type Source struct {
+	Name     string        // Name - identifies the source in Items and Health, the URL when empty
+	URL      string        // URL - the Atom feed
+	Interval time.Duration // Interval - between polls, the Aggregator's Interval when zero
+}
+
+func (s *Source) name() string {
+	if s.Name == "" {
+		return s.URL
+	}
+	return s.Name
+}
+
+// Item - an entry merged into the stream of an Aggregator
+type Item struct {
+	Source string     // Source - the Name of the Source the entry was first seen in
+	Entry  atom.Entry // Entry - a copy of the entry, its atom:source describes the origin feed
+}
+
+// Health - the status of a Source of an Aggregator
+type Health struct {
+	Source      string    // Source - the Name of the Source
+	URL         string    // URL - the feed of the Source
+	LastPoll    time.Time // LastPoll - when the source was last polled
+	LastSuccess time.Time // LastSuccess - when the feed was last retrieved or found not modified
+	LastError   error     // LastError - the error of the last failed poll
+	LastErrorAt time.Time // LastErrorAt - when the last poll failed
+	Failures    int       // Failures - the number of consecutive failed polls
+	Entries     int       // Entries - the number of entries in the feed when it was last retrieved
+}
+
+// Healthy reports whether the last poll of the source succeeded
+func (h *Health) Healthy() bool {
+	return !h.LastSuccess.IsZero() && h.Failures == 0
+}
+
+// Aggregator - polls many feeds concurrently and merges their entries into a
+// single stream. An alert published in several feeds, or in successive
+// versions of a feed, is only reported the first time it is seen, alerts are
+// identified by their CAP identifier, sender and sent time. The alert linked
+// from an entry without an embedded alert is retrieved to identify it, once
+// for each version of the entry, so an alert embedded in one feed and linked
+// from another is reported once.
+type Aggregator struct {
+	// Client - used to poll the sources, a new atom.NewClient() when nil. The
+	// client makes conditional requests so it should not be used to retrieve
+	// the same feeds elsewhere, or the Aggregator will miss their changes.
+	Client *atom.Client
+	// Sources - the feeds to poll
+	Sources []Source
+	// Interval - between polls of sources without an Interval,
+	// atom.DefaultWatchInterval when zero
+	Interval time.Duration
+	// Window - the number of alerts remembered to detect duplicates,
+	// DefaultWindow when zero
+	Window int
+
+	mu     sync.Mutex
+	health []Health
+}
+
+// Run polls the sources until ctx is done, then closes the returned channel.
+// Each source is polled immediately and then every Interval.
+func (a *Aggregator) Run(ctx context.Context) <-chan Item {
+	client := a.Client
+	if client == nil {
+		client = atom.NewClient()
+	}
+	window := a.Window
+	if window == 0 {
+		window = DefaultWindow
+	}
+	dedup := cap.NewDeduplicator(window)
+	a.mu.Lock()
+	a.health = make([]Health, len(a.Sources))
+	for i := range a.Sources {
+		a.health[i] = Health{Source: a.Sources[i].name(), URL: a.Sources[i].URL}
+	}
+	a.mu.Unlock()
+
+	items := make(chan Item)
+	var wg sync.WaitGroup
+	for i := range a.Sources {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			a.run(ctx, client, dedup, i, items)
+		}(i)
+	}
+	go func() {
+		wg.Wait()
+		close(items)
+	}()
+	return items
+}
+
+// Health returns the status of each source, in the order of Sources
+func (a *Aggregator) Health() []Health {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]Health(nil), a.health...)
+}
+
+func (a *Aggregator) run(ctx context.Context, client *atom.Client, dedup *cap.Deduplicator, i int, items chan<- Item) {
+	source := a.Sources[i]
+	interval := source.Interval
+	if interval == 0 {
+		interval = a.Interval
+	}
+	if interval == 0 {
+		interval = atom.DefaultWatchInterval
+	}
+	// keys - of the entries of the last retrieved feed, by atom.CacheKey
+	keys := make(map[string]string)
+	for {
+		feed, _, err := client.GetFeedIfModified(ctx, source.URL)
+		if ctx.Err() != nil {
+			return
+		}
+		a.record(i, feed, err)
+		if err == nil {
+			retrieved := make(map[string]string, len(feed.Entries))
+			for _, entry := range feed.Entries {
+				cacheKey := atom.CacheKey(&entry)
+				key, ok := keys[cacheKey]
+				if !ok {
+					key = linkedKey(ctx, client, &entry)
+				}
+				retrieved[cacheKey] = key
+				if dedup.DuplicateKey(key) {
+					continue
+				}
+				item := Item{Source: source.name(), Entry: attribute(entry, feed, source.URL)}
+				select {
+				case items <- item:
+				case <-ctx.Done():
+					return
+				}
+			}
+			keys = retrieved
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// record updates the health of source i after a poll
+func (a *Aggregator) record(i int, feed *atom.Feed, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	health := &a.health[i]
+	now := time.Now()
+	health.LastPoll = now
+	switch err {
+	case nil:
+		health.Entries = len(feed.Entries)
+		fallthrough
+	case atom.ErrNotModified:
+		health.LastSuccess = now
+		health.Failures = 0
+	default:
+		health.LastError = err
+		health.LastErrorAt = now
+		health.Failures++
+	}
+}
+
+// Key returns the identity of the alert of the entry, from its identifier,
+// sender and sent time. The alert embedded in the entry is used if there is
+// one, otherwise the entry's ID, author and published time. Key does not
+// retrieve linked alerts, so an entry linking an alert and one embedding it
+// only have the same Key if the entry's ID is the alert's identifier.
+func Key(entry *atom.Entry) string {
+	if embedded, _, err := entry.EmbeddedAlert(); err == nil {
+		return alertKey(&embedded.Alert)
+	}
+	return alertKey(entry.ToAlert())
+}
+
+// linkedKey returns the Key of the entry from the alert it links to, when it
+// has no embedded alert, or its Key if the alert cannot be retrieved
+func linkedKey(ctx context.Context, client *atom.Client, entry *atom.Entry) string {
+	if _, _, err := entry.EmbeddedAlert(); err != atom.ErrNoEmbeddedAlert {
+		return Key(entry)
+	}
+	if _, err := entry.AlertURL(); err != nil {
+		return Key(entry)
+	}
+	alert, _, err := client.GetEntryAlert(ctx, entry)
+	if err != nil {
+		return Key(entry)
+	}
+	return alertKey(&alert.Alert)
+}
+
+func alertKey(alert *cap.Alert) string {
+	sent := string(alert.Sent)
+	if t, err := cap.TimeParse(alert.Sent); err == nil {
+		// the same time may be written with different offsets
+		sent = t.UTC().Format(time.RFC3339)
+	}
+	return strings.Join([]string{strings.TrimSpace(alert.Identifier), strings.TrimSpace(alert.Sender), sent}, "\x00")
+}
+
+// attribute returns a copy of the entry whose atom:source describes the feed
+// retrieved from feedURL, entries which already name their source keep it
+func attribute(entry atom.Entry, feed *atom.Feed, feedURL string) atom.Entry {
+	if len(entry.Source) > 0 {
+		return entry
+	}
+	source := atom.Source{
+		ID:          feed.ID,
+		Title:       feed.Title,
+		Updated:     string(feed.Updated),
+		Author:      feed.Author,
+		Link:        feed.Link,
+		Category:    feed.Category,
+		Contributor: feed.Contributor,
+		Generator:   feed.Generator,
+		Icon:        feed.Icon,
+		Logo:        feed.Logo,
+		Rights:      feed.Rights,
+		SubTitle:    feed.SubTitle,
+	}
+	hasSelf := false
+	for _, link := range feed.Link {
+		hasSelf = hasSelf || link.Relation() == atom.RelSelf
+	}
+	if !hasSelf {
+		source.Link = append(append([]atom.Link(nil), feed.Link...), atom.Link{Href: feedURL, Rel: atom.RelSelf})
+	}
+	entry.Source = []atom.Source{source}
+	return entry
+}
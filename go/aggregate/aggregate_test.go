@@ -0,0 +1,255 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregate
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/cap/go/atom"
+	"github.com/stretchr/testify/assert"
+)
+
+func testEntry(id string, published atom.TimeStr) atom.Entry {
+	return atom.Entry{
+		ID:        id,
+		Title:     atom.Text{Content: id},
+		Updated:   published,
+		Published: published,
+		Author:    []atom.Person{{Name: "w-nws.webmaster@noaa.gov"}},
+	}
+}
+
+// newFeedServer serves the feeds by path, the feeds can be replaced while serving
+func newFeedServer(t *testing.T, feeds map[string]*atom.Feed) (*httptest.Server, *sync.Mutex) {
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		feed, ok := feeds[r.URL.Path]
+		var data []byte
+		var err error
+		if ok {
+			data, err = feed.Marshal()
+		}
+		mu.Unlock()
+		if !ok {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "application/atom+xml")
+		w.Write(data)
+	}))
+	return server, &mu
+}
+
+func receive(t *testing.T, items <-chan Item, n int) []Item {
+	var received []Item
+	timeout := time.After(5 * time.Second)
+	for len(received) < n {
+		select {
+		case item := <-items:
+			received = append(received, item)
+		case <-timeout:
+			t.Fatalf("received %d of %d items", len(received), n)
+		}
+	}
+	return received
+}
+
+func TestAggregatorMergesAndDeduplicatesEntries(t *testing.T) {
+	national := &atom.Feed{
+		ID:      "https://alerts.weather.gov/cap/us.php?x=0",
+		Title:   atom.Text{Content: "National alerts"},
+		Updated: "2018-08-15T14:52:00-08:00",
+		Entries: []atom.Entry{
+			testEntry("urn:alert:1", "2018-08-15T14:52:00-08:00"),
+			testEntry("urn:alert:2", "2018-08-15T14:53:00-08:00"),
+		},
+	}
+	state := &atom.Feed{
+		ID:      "https://alerts.weather.gov/cap/tx.php?x=1",
+		Title:   atom.Text{Content: "Texas alerts"},
+		Updated: "2018-08-15T14:52:00-08:00",
+		Link:    []atom.Link{{Href: "/tx", Rel: atom.RelSelf}},
+		Entries: []atom.Entry{
+			// the same alert, with its sent time at a different offset
+			testEntry("urn:alert:2", "2018-08-15T22:53:00Z"),
+			testEntry("urn:alert:3", "2018-08-15T14:54:00-08:00"),
+		},
+	}
+	server, mu := newFeedServer(t, map[string]*atom.Feed{"/us": national, "/tx": state})
+	defer server.Close()
+
+	aggregator := &Aggregator{
+		Client: &atom.Client{},
+		Sources: []Source{
+			{Name: "national", URL: server.URL + "/us"},
+			{URL: server.URL + "/tx"},
+			{Name: "offline", URL: server.URL + "/offline"},
+		},
+		Interval: 10 * time.Millisecond,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	items := aggregator.Run(ctx)
+
+	received := receive(t, items, 3)
+	sort.Slice(received, func(i, j int) bool { return received[i].Entry.ID < received[j].Entry.ID })
+	ids := []string{received[0].Entry.ID, received[1].Entry.ID, received[2].Entry.ID}
+	assert.Equal(t, []string{"urn:alert:1", "urn:alert:2", "urn:alert:3"}, ids)
+
+	third := received[2]
+	assert.Equal(t, server.URL+"/tx", third.Source)
+	source := third.Entry.Source[0]
+	assert.Equal(t, "https://alerts.weather.gov/cap/tx.php?x=1", source.ID)
+	assert.Equal(t, "Texas alerts", source.Title.Content)
+	assert.Equal(t, []atom.Link{{Href: server.URL + "/tx", Rel: atom.RelSelf}}, source.Link)
+	// the national feed has no self link, so the source URL is added
+	first := received[0]
+	assert.Equal(t, "national", first.Source)
+	assert.Equal(t, atom.Link{Href: server.URL + "/us", Rel: atom.RelSelf}, first.Entry.Source[0].Link[0])
+
+	// a new alert is reported, the alerts seen before are not
+	mu.Lock()
+	national.Entries = append(national.Entries, testEntry("urn:alert:4", "2018-08-15T14:55:00-08:00"))
+	mu.Unlock()
+	received = receive(t, items, 1)
+	assert.Equal(t, "urn:alert:4", received[0].Entry.ID)
+
+	// wait for every source to be polled again
+	polled := time.Now()
+	health := aggregator.Health()
+	for i := 0; i < len(health); {
+		if health[i].LastPoll.After(polled) {
+			i++
+			continue
+		}
+		time.Sleep(time.Millisecond)
+		health = aggregator.Health()
+	}
+	assert.Equal(t, "national", health[0].Source)
+	assert.True(t, health[0].Healthy())
+	assert.Equal(t, 3, health[0].Entries)
+	assert.Equal(t, 2, health[1].Entries)
+	assert.False(t, health[2].Healthy())
+	assert.True(t, health[2].LastSuccess.IsZero())
+	assert.Equal(t, "HTTP status code: 503", health[2].LastError.Error())
+	assert.NotEqual(t, 0, health[2].Failures)
+
+	cancel()
+	for range items {
+	}
+	_, ok := <-items
+	assert.False(t, ok)
+}
+
+func TestAggregatorDeduplicatesEmbeddedAndLinkedAlert(t *testing.T) {
+	alertXML, err := ioutil.ReadFile("../../resources/cap_amber_alert_example.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	embedded := alertXML[bytes.Index(alertXML, []byte("?>"))+2:]
+	inline := &atom.Feed{ID: "urn:feed:inline", Title: atom.Text{Content: "Inline alerts"}, Updated: "2018-08-15T14:52:00-08:00"}
+	entry := testEntry("urn:entry:inline", "2018-08-15T14:52:00-08:00")
+	entry.Content = atom.Text{Type: "application/cap+xml", Body: string(bytes.TrimSpace(embedded))}
+	inline.Entries = []atom.Entry{entry}
+	linked := &atom.Feed{ID: "urn:feed:linked", Title: atom.Text{Content: "Linked alerts"}, Updated: "2018-08-15T14:52:00-08:00"}
+	entry = testEntry("urn:entry:linked", "2018-08-15T14:53:00-08:00")
+	entry.Link = []atom.Link{{Href: "/alert.xml", Type: "application/cap+xml"}}
+	linked.Entries = []atom.Entry{entry}
+
+	feeds, _ := newFeedServer(t, map[string]*atom.Feed{"/inline": inline, "/linked": linked})
+	defer feeds.Close()
+	var mu sync.Mutex
+	alertRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/alert.xml" {
+			feeds.Config.Handler.ServeHTTP(w, r)
+			return
+		}
+		mu.Lock()
+		alertRequests++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/cap+xml")
+		w.Write(alertXML)
+	}))
+	defer server.Close()
+	linked.Entries[0].Link[0].Href = server.URL + "/alert.xml"
+
+	inlineEntry, linkedEntry := inline.Entries[0], linked.Entries[0]
+	assert.NotEqual(t, Key(&inlineEntry), Key(&linkedEntry))
+
+	aggregator := &Aggregator{
+		Client:   &atom.Client{},
+		Sources:  []Source{{URL: server.URL + "/inline"}, {URL: server.URL + "/linked"}},
+		Interval: 10 * time.Millisecond,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	items := aggregator.Run(ctx)
+	receive(t, items, 1)
+
+	// the other source reports the same alert, which is not received
+	polled := time.Now()
+	health := aggregator.Health()
+	for i := 0; i < len(health); {
+		if health[i].LastSuccess.After(polled) {
+			i++
+			continue
+		}
+		time.Sleep(time.Millisecond)
+		health = aggregator.Health()
+	}
+	select {
+	case item := <-items:
+		t.Fatalf("received %s again from %s", item.Entry.ID, item.Source)
+	default:
+	}
+	// the linked alert is retrieved once for the version of its entry
+	mu.Lock()
+	assert.Equal(t, 1, alertRequests)
+	mu.Unlock()
+}
+
+func TestKeyUsesIdentifierSenderAndSent(t *testing.T) {
+	a := testEntry("urn:alert:1", "2018-08-15T14:52:00-08:00")
+	b := testEntry("urn:alert:1", "2018-08-15T22:52:00Z")
+	b.Title = atom.Text{Content: "retitled"}
+	assert.Equal(t, Key(&a), Key(&b))
+
+	b.Author = []atom.Person{{Name: "other@example.com"}}
+	assert.NotEqual(t, Key(&a), Key(&b))
+	c := testEntry("urn:alert:1", "2018-08-15T14:53:00-08:00")
+	assert.NotEqual(t, Key(&a), Key(&c))
+}
+
+func TestAttributeKeepsExistingSource(t *testing.T) {
+	entry := testEntry("urn:alert:1", "2018-08-15T14:52:00-08:00")
+	entry.Source = []atom.Source{{ID: "urn:origin"}}
+	attributed := attribute(entry, &atom.Feed{ID: "urn:relay"}, "http://relay.example.com/feed")
+	assert.Equal(t, "urn:origin", attributed.Source[0].ID)
+}
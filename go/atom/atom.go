@@ -17,11 +17,9 @@ limitations under the License.
 package atom
 
 import (
+	"context"
 	"encoding/xml"
-	"fmt"
-	"io/ioutil"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/IBM/cap/go/cap"
@@ -31,6 +29,10 @@ import (
 // NwsNationalAtomFeedURL is the URL for the NWS National Atom feed
 const NwsNationalAtomFeedURL string = "https://alerts.weather.gov/cap/us.php?x=1"
 
+// nwsNationalAtomFeedPath - the path of NwsNationalAtomFeedURL, resolved
+// against the BaseURL of clients which have one
+const nwsNationalAtomFeedPath string = "/cap/us.php?x=1"
+
 // TODO consider adding enums
 // TODO add json conversion
 
@@ -58,8 +60,8 @@ type Feed struct {
 
 // CommonAttributes - this struct is for common atom attributes
 type CommonAttributes struct {
-	Base string `xml:"base,attr,omitempty"` // Base - if not empty must be a valid url.URL.
-	Lang string `xml:"lang,attr,omitempty"`
+	Base string `xml:"http://www.w3.org/XML/1998/namespace base,attr,omitempty"` // Base - xml:base, if not empty must be a valid url.URL.
+	Lang string `xml:"http://www.w3.org/XML/1998/namespace lang,attr,omitempty"` // Lang - xml:lang
 }
 
 // Text - this struct is for an xml element that may include chardata and
@@ -74,7 +76,7 @@ type Text struct {
 	//  If type="xhtml", then this element contains inline xhtml, wrapped in a div element.
 	Type string `xml:"type,attr,omitempty"`
 	Src  string `xml:"src,attr,omitempty"` // Src if present must be a valid url.URL.
-	Body string `xml:",innerxml"`          // use Body for xhtml text
+	Body string `xml:",innerxml"`          // use Body for xhtml text, its namespaces are declared within it
 }
 
 // Person - describe a person, corporation, or similar entity. It has one
@@ -114,6 +116,9 @@ type Category struct {
 	Term    string `xml:"term,attr"`             // identifies the category
 	Scheme  string `xml:"scheme,attr,omitempty"` // identifies the categorization scheme via a URI
 	Label   string `xml:"label,attr,omitempty"`  // provides a human-readable label for display
+	// Space - the namespace the category was read with, a CAP namespace for
+	// the cap:category elements of NWS feeds
+	Space string `xml:"-"`
 }
 
 // Generator - Identifies the software used to generate the feed, for debugging
@@ -213,17 +218,8 @@ type Source struct { // TODO if possible replace these struct elements with "Fee
 	SubTitle    Text       `xml:"subtitle,omitempty"`    // contains a human-readable description or subtitle for the source feed
 }
 
-// Geocode - as specified by the NWS Atom feed
-type Geocode struct {
-	Names  []string `xml:"valueName,omitempty"`
-	Values []string `xml:"value,omitempty"`
-}
-
 // Extension - used for adding custom content to an element
-type Extension struct {
-	XMLName xml.Name
-	XML     string `xml:",innerxml"`
-}
+type Extension = shared.Extension
 
 // NamedValue -
 type NamedValue = shared.NamedValue
@@ -243,65 +239,48 @@ func TimeParse(t TimeStr) (time.Time, error) {
 	return shared.TimeParse(t)
 }
 
-// GetGeocodes returns back an array of values for the Geocode element with the same name
-func (g *Geocode) GetGeocodes(name string) []string {
-	for index, value := range g.Names {
-		if value == name {
-			return strings.Split(g.Values[index], " ")
-		}
-	}
-	return []string{}
-}
-
 // GetParameter returns the value for the first parameter with the specified name or ""
 func (e *Entry) GetParameter(name string) string {
 	return shared.Search(&e.Parameter, name)
 }
 
-// GetAlert retrieves an Alert from a link's href attribute
-func (l *Link) GetAlert() (*cap.Alert11, []byte, error) {
-	body, err := handleHTTPResponse(http.Get(l.Href))
+// GetAlert retrieves an Alert from a link's href attribute using the DefaultClient
+func (l *Link) GetAlert() (*cap.VersionedAlert, []byte, error) {
+	alertURL, err := l.URL()
 	if err != nil {
 		return nil, nil, err
 	}
+	return DefaultClient.GetAlert(context.Background(), alertURL)
+}
 
-	var alert cap.Alert11
-	err = xml.Unmarshal(body, &alert)
-	if err != nil {
-		return nil, nil, err
-	}
-	return &alert, body, nil
+// GetAlert retrieves the Alert linked from the entry using the DefaultClient,
+// see Entry.AlertLink for how the link is chosen
+func (e *Entry) GetAlert() (*cap.VersionedAlert, []byte, error) {
+	return DefaultClient.GetEntryAlert(context.Background(), e)
 }
 
-// GetFeed retrieves the main National Weather Service CAP v1.1 ATOM feed
+// GetFeed retrieves the main National Weather Service CAP v1.1 ATOM feed using the DefaultClient
 func GetFeed() (*Feed, []byte, error) {
-	body, err := handleHTTPResponse(http.Get(NwsNationalAtomFeedURL))
-	if err != nil {
-		return nil, nil, err
-	}
-
-	var downloadedFeed Feed
-	err = xml.Unmarshal(body, &downloadedFeed)
-	if err != nil {
-		return nil, nil, err
-	}
-	return &downloadedFeed, body, nil
+	return DefaultClient.GetFeed(context.Background(), NwsNationalAtomFeedURL)
 }
 
-func handleHTTPResponse(r *http.Response, err error) ([]byte, error) {
+// handleHTTPResponse returns the decoded body of a 200 OK response which
+// passes check, if not nil, bodies larger than maxBodySize are rejected unless
+// it is negative
+func handleHTTPResponse(r *http.Response, err error, maxBodySize int64, check func(*http.Response) error) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	if r.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP status code: %d", r.StatusCode)
+	if r.Body != nil {
+		defer r.Body.Close()
 	}
-	if r.ContentLength == 0 {
-		return nil, fmt.Errorf("No content")
+	if r.StatusCode != http.StatusOK {
+		return nil, newHTTPError(r)
 	}
-	defer r.Body.Close()
-	body, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		return nil, err
+	if check != nil {
+		if err := check(r); err != nil {
+			return nil, err
+		}
 	}
-	return body, nil
+	return readBody(r, maxBodySize)
 }
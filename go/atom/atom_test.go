@@ -81,8 +81,8 @@ func TestUnmarshalNWSAtomFeedEntryHasProperValues(t *testing.T) {
 	assert.Equal(t, entry.Certainty, "Likely")
 	assert.Equal(t, entry.AreaDesc, "Eastern Beaufort Sea Coast")
 	assert.Equal(t, entry.Polygon[0], "")
-	assert.Equal(t, len(entry.Geocode.Names), 2)
-	assert.Equal(t, len(entry.Geocode.Values), 2)
+	assert.Equal(t, len(entry.Geocode.Pairs), 2)
+	assert.Equal(t, len(entry.Geocode.Warnings), 0)
 }
 
 func TestUnmarshalNWSAtomFeedEntryGeocodeHasProperValues(t *testing.T) {
@@ -119,14 +119,14 @@ func TestLinkFollowAlertReturnsErrorForInvalidURL(t *testing.T) {
 
 func TestHandleHttpResponseReturnsStartingErr(t *testing.T) {
 	existingError := errors.New("prexisting error")
-	_, err := handleHTTPResponse(nil, existingError)
+	_, err := handleHTTPResponse(nil, existingError, DefaultMaxBodySize, checkContentType)
 	assert.Equal(t, existingError, err)
 }
 
 func TestHandleHttpResponseReturnsErrOnNon200StatusCode(t *testing.T) {
 	var response http.Response
 	response.StatusCode = 400
-	_, err := handleHTTPResponse(&response, nil)
+	_, err := handleHTTPResponse(&response, nil, DefaultMaxBodySize, checkContentType)
 	assert.Equal(t, "HTTP status code: 400", err.Error())
 }
 
@@ -134,6 +134,6 @@ func TestHandleHttpResponseReturnsErrOnZeroContentLength(t *testing.T) {
 	var response http.Response
 	response.StatusCode = 200
 	response.ContentLength = 0
-	_, err := handleHTTPResponse(&response, nil)
+	_, err := handleHTTPResponse(&response, nil, DefaultMaxBodySize, checkContentType)
 	assert.Equal(t, "No content", err.Error())
 }
@@ -0,0 +1,120 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without making a request while the circuit
+// breaker of the host is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// CircuitBreaker - stops requests to a host after Threshold consecutive
+// transient failures. Once Cooldown has passed a single request is let
+// through, its success closes the circuit and its failure opens it again. A
+// CircuitBreaker is safe for concurrent use.
+type CircuitBreaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*circuit
+}
+
+// circuit - the state of a single host
+type circuit struct {
+	failures int
+	openedAt time.Time
+	probing  bool // probing - whether the request after the cooldown is in flight
+}
+
+// NewCircuitBreaker creates a CircuitBreaker
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{Threshold: threshold, Cooldown: cooldown}
+}
+
+// Open - whether requests to the host are currently refused
+func (b *CircuitBreaker) Open(host string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.hosts[host]
+	return c != nil && b.tripped(c) && (c.probing || time.Since(c.openedAt) < b.Cooldown)
+}
+
+// allow returns ErrCircuitOpen when a request to the host may not be made
+func (b *CircuitBreaker) allow(host string) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.hosts[host]
+	if c == nil || !b.tripped(c) {
+		return nil
+	}
+	if c.probing || time.Since(c.openedAt) < b.Cooldown {
+		return ErrCircuitOpen
+	}
+	c.probing = true
+	return nil
+}
+
+// record records the outcome of a request to the host
+func (b *CircuitBreaker) record(host string, failed bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !failed {
+		delete(b.hosts, host)
+		return
+	}
+	if b.hosts == nil {
+		b.hosts = make(map[string]*circuit)
+	}
+	c := b.hosts[host]
+	if c == nil {
+		c = &circuit{}
+		b.hosts[host] = c
+	}
+	c.failures++
+	c.probing = false
+	if b.tripped(c) {
+		c.openedAt = time.Now()
+	}
+}
+
+// release records a request to the host whose outcome says nothing about the
+// host, e.g. one which was canceled, letting another request probe the host
+func (b *CircuitBreaker) release(host string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c := b.hosts[host]; c != nil {
+		c.probing = false
+	}
+}
+
+func (b *CircuitBreaker) tripped(c *circuit) bool {
+	return b.Threshold > 0 && c.failures >= b.Threshold
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// AlertCache - stores retrieved CAP alert documents, keyed by CacheKey
+type AlertCache interface {
+	Get(key string) ([]byte, bool)     // Get - returns the document stored under key
+	Put(key string, data []byte) error // Put - stores the document under key
+}
+
+// CacheKey returns the key of the alert linked from the entry, the key
+// changes whenever the entry's ID or Updated time changes
+func CacheKey(entry *Entry) string {
+	sum := sha256.Sum256([]byte(entry.ID + "\n" + string(entry.Updated)))
+	return hex.EncodeToString(sum[:])
+}
+
+// DiskCache - an AlertCache storing each alert document in a file in Dir
+type DiskCache struct {
+	Dir string
+}
+
+// NewDiskCache creates a DiskCache, creating dir if it does not exist
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &DiskCache{Dir: dir}, nil
+}
+
+// Get returns the document stored under key
+func (d *DiskCache) Get(key string) ([]byte, bool) {
+	data, err := ioutil.ReadFile(d.path(key))
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Put stores the document under key, the file is replaced atomically so
+// concurrent readers never see a partial document
+func (d *DiskCache) Put(key string, data []byte) error {
+	tmp, err := ioutil.TempFile(d.Dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), d.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// Prune removes the documents stored before the given time, as alerts are
+// keyed by their Updated time superseded versions are otherwise never removed
+func (d *DiskCache) Prune(before time.Time) error {
+	files, err := ioutil.ReadDir(d.Dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".xml") || !file.ModTime().Before(before) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(d.Dir, file.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *DiskCache) path(key string) string {
+	return filepath.Join(d.Dir, key+".xml")
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheKeyChangesWithUpdated(t *testing.T) {
+	entry := Entry{ID: "urn:test:1", Updated: "2018-08-15T14:52:00-08:00"}
+	key := CacheKey(&entry)
+	assert.Equal(t, key, CacheKey(&entry))
+	entry.Updated = "2018-08-15T15:52:00-08:00"
+	assert.NotEqual(t, key, CacheKey(&entry))
+}
+
+func TestDiskCachePutGetAndPrune(t *testing.T) {
+	dir, err := ioutil.TempDir("", "atom-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cache, err := NewDiskCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ok := cache.Get("a")
+	assert.False(t, ok)
+	assert.Nil(t, cache.Put("a", []byte("<alert/>")))
+	data, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "<alert/>", string(data))
+
+	assert.Nil(t, cache.Prune(time.Now().Add(-time.Hour)))
+	_, ok = cache.Get("a")
+	assert.True(t, ok)
+	assert.Nil(t, cache.Prune(time.Now().Add(time.Hour)))
+	_, ok = cache.Get("a")
+	assert.False(t, ok)
+}
@@ -0,0 +1,385 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/IBM/cap/go/cap"
+)
+
+// DefaultUserAgent is sent by clients without a UserAgent, the NWS rejects
+// requests which do not identify the application making them
+const DefaultUserAgent string = "IBM/cap (https://github.com/IBM/cap)"
+
+// DefaultTimeout is the overall request timeout of clients created by NewClient
+const DefaultTimeout time.Duration = 30 * time.Second
+
+// Accept headers sent when retrieving feeds and alerts
+const (
+	feedAccept    string = "application/atom+xml, application/xml;q=0.9"
+	anyFeedAccept string = "application/atom+xml, application/rss+xml, application/xml;q=0.9"
+	alertAccept   string = "application/cap+xml, application/xml;q=0.9"
+)
+
+// DefaultClient is the Client used by GetFeed and Link.GetAlert
+var DefaultClient = NewClient()
+
+// ErrNotModified is returned by GetFeedIfModified when the feed is unchanged
+var ErrNotModified = errors.New("not modified")
+
+// Client - retrieves Atom feeds and CAP alerts over HTTP
+type Client struct {
+	// HTTPClient - used to make requests, http.DefaultClient is used when nil
+	HTTPClient *http.Client
+	// BaseURL - if set, relative feed and alert URLs are resolved against it,
+	// e.g. to point at a mirror or an httptest server
+	BaseURL string
+	// UserAgent - sent with every request, DefaultUserAgent is used when empty
+	UserAgent string
+	// Cache - if set, alerts retrieved by GetEntryAlert are stored in and
+	// served from the cache
+	Cache AlertCache
+	// Retry - how transient failures are retried, requests are not retried
+	// when nil
+	Retry *RetryPolicy
+	// Breaker - if set, stops requests to hosts which keep failing
+	Breaker *CircuitBreaker
+	// MaxBodySize - the largest response body accepted once decoded,
+	// DefaultMaxBodySize is used when zero and bodies are unlimited when
+	// negative
+	MaxBodySize int64
+
+	mu         sync.Mutex
+	validators map[string]validator // validators - by URL, for GetFeedIfModified
+}
+
+// validator - the cache validators returned with a feed
+type validator struct {
+	etag         string
+	lastModified string
+}
+
+// Circuit breaker settings of clients created by NewClient
+const (
+	DefaultBreakerThreshold int           = 5
+	DefaultBreakerCooldown  time.Duration = time.Minute
+)
+
+// NewClient creates a Client with DefaultTimeout, DefaultUserAgent,
+// DefaultRetryPolicy and a circuit breaker
+func NewClient() *Client {
+	retry := DefaultRetryPolicy
+	return &Client{
+		HTTPClient: &http.Client{Timeout: DefaultTimeout},
+		UserAgent:  DefaultUserAgent,
+		Retry:      &retry,
+		Breaker:    NewCircuitBreaker(DefaultBreakerThreshold, DefaultBreakerCooldown),
+	}
+}
+
+// GetFeed retrieves and parses the Atom feed at feedURL, the NWS national
+// feed is retrieved when feedURL is empty
+func (c *Client) GetFeed(ctx context.Context, feedURL string) (*Feed, []byte, error) {
+	feedURL = c.defaultFeed(feedURL)
+	body, _, err := c.get(ctx, feedURL, feedAccept, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.decodeFeed(feedURL, body)
+}
+
+// GetFeedDocument retrieves the feed at feedURL without parsing it, for feeds
+// which may be of another format such as RSS. The document is returned with
+// the URL it was retrieved from, which relative references resolve against.
+// The NWS national feed is retrieved when feedURL is empty.
+func (c *Client) GetFeedDocument(ctx context.Context, feedURL string) ([]byte, string, error) {
+	documentURL, err := c.resolve(c.defaultFeed(feedURL))
+	if err != nil {
+		return nil, "", err
+	}
+	body, _, err := c.get(ctx, documentURL, anyFeedAccept, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, documentURL, nil
+}
+
+// GetFeedIfModified retrieves and parses the Atom feed at feedURL like
+// GetFeed, but sends the ETag and Last-Modified validators of the previous
+// response for the URL, returning ErrNotModified when the feed is unchanged
+func (c *Client) GetFeedIfModified(ctx context.Context, feedURL string) (*Feed, []byte, error) {
+	feedURL = c.defaultFeed(feedURL)
+	v := c.validator(feedURL)
+	body, _, err := c.get(ctx, feedURL, feedAccept, &v)
+	if err != nil {
+		return nil, nil, err
+	}
+	feed, body, err := c.decodeFeed(feedURL, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	// only remember the validators of feeds which could be parsed
+	c.setValidator(feedURL, v)
+	return feed, body, nil
+}
+
+// GetEntryAlert retrieves and parses the CAP alert linked from the entry,
+// when the client has a Cache the alert is only retrieved if the cache has no
+// alert for the entry's ID and Updated time, an alert which cannot be stored
+// in the cache is still returned. An alert embedded in the entry's content is
+// returned without making a request.
+func (c *Client) GetEntryAlert(ctx context.Context, entry *Entry) (*cap.VersionedAlert, []byte, error) {
+	if alert, body, err := entry.EmbeddedAlert(); err != ErrNoEmbeddedAlert {
+		return alert, body, err
+	}
+	alertURL, err := entry.AlertURL()
+	if err != nil {
+		return nil, nil, err
+	}
+	key := CacheKey(entry)
+	if c.Cache != nil {
+		if body, ok := c.Cache.Get(key); ok {
+			alert, err := parseAlert(body, "")
+			if err == nil {
+				return alert, body, nil
+			}
+		}
+	}
+	alert, body, err := c.GetAlert(ctx, alertURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.Cache != nil {
+		// the alert was retrieved, failing to cache it only costs a request
+		// the next time
+		c.Cache.Put(key, body)
+	}
+	return alert, body, nil
+}
+
+// GetAlert retrieves and parses the CAP alert at alertURL, the CAP version is
+// detected from the namespace of the alert. A *ContentTypeError is returned
+// for responses which are not XML, e.g. an HTML error page, and a
+// *cap.NotCAPError for XML documents which are not CAP alerts.
+func (c *Client) GetAlert(ctx context.Context, alertURL string) (*cap.VersionedAlert, []byte, error) {
+	body, contentType, err := c.get(ctx, alertURL, alertAccept, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	alert, err := parseAlert(body, contentType)
+	if err != nil {
+		return nil, nil, err
+	}
+	return alert, body, nil
+}
+
+func parseFeed(body []byte) (*Feed, []byte, error) {
+	var downloadedFeed Feed
+	err := xml.Unmarshal(body, &downloadedFeed)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &downloadedFeed, body, nil
+}
+
+// decodeFeed parses the feed retrieved from feedURL and resolves its links
+func (c *Client) decodeFeed(feedURL string, body []byte) (*Feed, []byte, error) {
+	feed, body, err := parseFeed(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if documentURL, err := c.resolve(feedURL); err == nil {
+		feed.ResolveLinks(documentURL)
+	}
+	return feed, body, nil
+}
+
+// parseAlert parses a CAP alert of any version served as contentType, which
+// is empty when unknown
+func parseAlert(body []byte, contentType string) (*cap.VersionedAlert, error) {
+	alert, err := cap.ParseVersionedAlert(body)
+	if notCAP, ok := err.(*cap.NotCAPError); ok {
+		notCAP.ContentType = contentType
+	}
+	return alert, err
+}
+
+// Get retrieves rawURL, resolved against BaseURL, with the retries, circuit
+// breaker, body size limit and content decoding of GetFeed and GetAlert, and
+// returns the body with the Content-Type of the response. Unlike them it
+// accepts a response of any media type, e.g. the JSON of an API. A non 200
+// response is returned as an *HTTPError.
+func (c *Client) Get(ctx context.Context, rawURL string, accept string) ([]byte, string, error) {
+	return c.fetch(ctx, rawURL, accept, nil, nil)
+}
+
+// get retrieves rawURL like Get, checking that the response is of an XML
+// media type. When v is not nil the request is conditional on the validators
+// in v, which are updated from the response. ErrNotModified is returned on a
+// 304.
+func (c *Client) get(ctx context.Context, rawURL string, accept string, v *validator) ([]byte, string, error) {
+	return c.fetch(ctx, rawURL, accept, v, checkContentType)
+}
+
+// fetch retrieves rawURL for Get and get, retrying transient failures
+// according to the Retry policy. When check is not nil it is applied to a 200
+// response before its body is read.
+func (c *Client) fetch(ctx context.Context, rawURL string, accept string, v *validator, check func(*http.Response) error) ([]byte, string, error) {
+	resolved, err := c.resolve(rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+	u, err := url.Parse(resolved)
+	if err != nil {
+		return nil, "", err
+	}
+	var lastErr error
+	for n := 0; ; n++ {
+		if err := c.Breaker.allow(u.Host); err != nil {
+			if lastErr != nil {
+				// the failures of this request opened the circuit
+				return nil, "", lastErr
+			}
+			return nil, "", err
+		}
+		body, contentType, err := c.attempt(ctx, resolved, accept, v, check)
+		switch {
+		case err == nil || err == ErrNotModified:
+			c.Breaker.record(u.Host, false)
+			return body, contentType, err
+		case ctx.Err() != nil || !transient(err):
+			// neither a success nor a failure of the host
+			c.Breaker.release(u.Host)
+			return nil, "", err
+		}
+		c.Breaker.record(u.Host, true)
+		lastErr = err
+		delay, ok := c.Retry.backoff(n, err)
+		if !ok {
+			return nil, "", err
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return nil, "", err
+		}
+	}
+}
+
+// attempt makes a single request for fetch
+func (c *Client) attempt(ctx context.Context, resolved string, accept string, v *validator, check func(*http.Response) error) ([]byte, string, error) {
+	req, err := http.NewRequest(http.MethodGet, resolved, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", accept)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	req.Header.Set("User-Agent", c.userAgent())
+	if v != nil {
+		if v.etag != "" {
+			req.Header.Set("If-None-Match", v.etag)
+		}
+		if v.lastModified != "" {
+			req.Header.Set("If-Modified-Since", v.lastModified)
+		}
+	}
+	resp, err := c.httpClient().Do(req)
+	if err == nil && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		return nil, "", ErrNotModified
+	}
+	body, err := handleHTTPResponse(resp, err, c.maxBodySize(), check)
+	if err != nil {
+		return nil, "", err
+	}
+	if v != nil {
+		v.etag = resp.Header.Get("ETag")
+		v.lastModified = resp.Header.Get("Last-Modified")
+	}
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
+func (c *Client) validator(rawURL string) validator {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.validators[rawURL]
+}
+
+func (c *Client) setValidator(rawURL string, v validator) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.validators == nil {
+		c.validators = make(map[string]validator)
+	}
+	c.validators[rawURL] = v
+}
+
+// defaultFeed returns feedURL, or the NWS national feed when it is empty. With
+// a BaseURL the path of the national feed is resolved against it, so that a
+// mirror serves the default feed as well.
+func (c *Client) defaultFeed(feedURL string) string {
+	switch {
+	case feedURL != "":
+		return feedURL
+	case c.BaseURL != "":
+		return nwsNationalAtomFeedPath
+	}
+	return NwsNationalAtomFeedURL
+}
+
+// resolve resolves rawURL against BaseURL, when BaseURL is set
+func (c *Client) resolve(rawURL string) (string, error) {
+	if c.BaseURL == "" {
+		return rawURL, nil
+	}
+	base, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+	return c.HTTPClient
+}
+
+func (c *Client) maxBodySize() int64 {
+	if c.MaxBodySize == 0 {
+		return DefaultMaxBodySize
+	}
+	return c.MaxBodySize
+}
+
+func (c *Client) userAgent() string {
+	if c.UserAgent == "" {
+		return DefaultUserAgent
+	}
+	return c.UserAgent
+}
@@ -0,0 +1,319 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/IBM/cap/go/cap"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestServer serves the example feed at /feed and the example CAP alert at /alert
+func newTestServer(t *testing.T) *httptest.Server {
+	feed, err := ioutil.ReadFile("../../resources/nws_atom_feed_example.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alert, err := ioutil.ReadFile("../../resources/cap_amber_alert_example.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the NWS feed links to CAP v1.1 alerts
+	alert = bytes.Replace(alert, []byte(cap.Namespace12), []byte(cap.Namespace11), 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/atom+xml")
+		w.Write(feed)
+	})
+	mux.HandleFunc("/alert", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/cap+xml")
+		w.Write(alert)
+	})
+	mux.HandleFunc("/agent", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("User-Agent")))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestClientGetFeedFromBaseURL(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+	client := NewClient()
+	client.BaseURL = server.URL
+	feed, raw, err := client.GetFeed(context.Background(), "/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, 0, len(raw))
+	assert.Equal(t, "https://alerts.weather.gov/cap/us.php?x=0", feed.ID)
+	assert.Equal(t, 163, len(feed.Entries))
+}
+
+func TestClientGetsDefaultFeedFromBaseURL(t *testing.T) {
+	feed, err := ioutil.ReadFile("../../resources/nws_atom_feed_example.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.RequestURI() != nwsNationalAtomFeedPath {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/atom+xml")
+		w.Write(feed)
+	}))
+	defer server.Close()
+	client := &Client{BaseURL: server.URL}
+
+	parsed, _, err := client.GetFeed(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 163, len(parsed.Entries))
+
+	body, documentURL, err := client.GetFeedDocument(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, feed, body)
+	assert.Equal(t, server.URL+nwsNationalAtomFeedPath, documentURL)
+
+	_, _, err = client.GetFeedIfModified(context.Background(), "")
+	assert.NoError(t, err)
+}
+
+func TestClientGetAlert(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+	alert, _, err := NewClient().GetAlert(context.Background(), server.URL+"/alert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "KAR0-0306112239-SW", alert.Identifier)
+	assert.Equal(t, cap.Version11, alert.Version)
+}
+
+func TestClientGetAlertDetectsVersion(t *testing.T) {
+	alert, err := ioutil.ReadFile("../../resources/cap_amber_alert_example.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/1.2":
+			w.Header().Set("Content-Type", "application/cap+xml")
+			w.Write(alert)
+		case "/error":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html><body>Maintenance</body></html>"))
+		default:
+			w.Header().Set("Content-Type", "application/xml")
+			w.Write([]byte(`<feed xmlns="http://www.w3.org/2005/Atom"/>`))
+		}
+	}))
+	defer server.Close()
+
+	client := &Client{}
+	parsed, _, err := client.GetAlert(context.Background(), server.URL+"/1.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, cap.Version12, parsed.Version)
+	assert.Equal(t, "KAR0-0306112239-SW", parsed.Identifier)
+
+	_, _, err = client.GetAlert(context.Background(), server.URL+"/error")
+	_, ok := err.(*ContentTypeError)
+	assert.True(t, ok)
+
+	_, _, err = client.GetAlert(context.Background(), server.URL+"/feed")
+	_, ok = err.(*cap.NotCAPError)
+	assert.True(t, ok)
+	assert.Equal(t, "document of type application/xml is not a CAP alert: root element is <feed>", err.Error())
+}
+
+func TestClientGetAcceptsAnyMediaType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"status":404}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/geo+json")
+		w.Write([]byte(`{"type":"FeatureCollection"}`))
+	}))
+	defer server.Close()
+	client := &Client{BaseURL: server.URL}
+
+	body, contentType, err := client.Get(context.Background(), "/alerts", "application/geo+json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `{"type":"FeatureCollection"}`, string(body))
+	assert.Equal(t, "application/geo+json", contentType)
+
+	_, _, err = client.Get(context.Background(), "/missing", "application/geo+json")
+	httpErr, ok := err.(*HTTPError)
+	if !ok {
+		t.Fatalf("expected *HTTPError, got %v", err)
+	}
+	assert.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+	assert.Equal(t, `{"status":404}`, string(httpErr.Body))
+}
+
+func TestClientSendsUserAgent(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+	client := &Client{BaseURL: server.URL}
+	body, _, err := client.get(context.Background(), "/agent", "*/*", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, DefaultUserAgent, string(body))
+
+	client.UserAgent = "(example.com, ops@example.com)"
+	body, _, err = client.get(context.Background(), "/agent", "*/*", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "(example.com, ops@example.com)", string(body))
+}
+
+func TestClientReturnsErrOnCanceledContext(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := NewClient().GetFeed(ctx, server.URL+"/feed")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), context.Canceled.Error())
+}
+
+func TestClientReturnsErrOnNotFound(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+	_, _, err := NewClient().GetAlert(context.Background(), server.URL+"/missing")
+	assert.Equal(t, "HTTP status code: 404", err.Error())
+}
+
+func TestClientGetFeedIfModifiedReturnsErrNotModified(t *testing.T) {
+	feed, err := ioutil.ReadFile("../../resources/nws_atom_feed_example.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const etag = `"5b74b6ec-2a1f"`
+	const lastModified = "Wed, 15 Aug 2018 22:57:00 GMT"
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		w.Write(feed)
+	}))
+	defer server.Close()
+
+	client := NewClient()
+	_, raw, err := client.GetFeedIfModified(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, feed, raw)
+	_, _, err = client.GetFeedIfModified(context.Background(), server.URL)
+	assert.Equal(t, ErrNotModified, err)
+	assert.Equal(t, 2, len(requests))
+	assert.Equal(t, "", requests[0].Header.Get("If-None-Match"))
+	assert.Equal(t, etag, requests[1].Header.Get("If-None-Match"))
+	assert.Equal(t, lastModified, requests[1].Header.Get("If-Modified-Since"))
+
+	// GetFeed is never conditional
+	_, _, err = client.GetFeed(context.Background(), server.URL)
+	assert.Nil(t, err)
+}
+
+func TestClientGetEntryAlertUsesCache(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+	requests := 0
+	handler := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		handler.ServeHTTP(w, r)
+	})
+	dir, err := ioutil.TempDir("", "atom-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cache, err := NewDiskCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient()
+	client.Cache = cache
+	entry := Entry{ID: "urn:test:1", Updated: "2018-08-15T14:52:00-08:00", Link: []Link{{Href: server.URL + "/alert"}}}
+	for i := 0; i < 3; i++ {
+		alert, _, err := client.GetEntryAlert(context.Background(), &entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "KAR0-0306112239-SW", alert.Identifier)
+	}
+	assert.Equal(t, 1, requests)
+
+	entry.Updated = "2018-08-15T15:52:00-08:00"
+	_, _, err = client.GetEntryAlert(context.Background(), &entry)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, requests)
+}
+
+// failingCache - an AlertCache which stores nothing
+type failingCache struct{}
+
+func (failingCache) Get(key string) ([]byte, bool) { return nil, false }
+
+func (failingCache) Put(key string, data []byte) error { return errors.New("disk full") }
+
+func TestClientGetEntryAlertIgnoresCacheErrors(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+	client := &Client{Cache: failingCache{}}
+	entry := Entry{ID: "urn:test:1", Updated: "2018-08-15T14:52:00-08:00", Link: []Link{{Href: server.URL + "/alert"}}}
+	alert, body, err := client.GetEntryAlert(context.Background(), &entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "KAR0-0306112239-SW", alert.Identifier)
+	assert.NotEqual(t, 0, len(body))
+}
+
+func TestClientGetEntryAlertReturnsErrWithoutLink(t *testing.T) {
+	_, _, err := NewClient().GetEntryAlert(context.Background(), &Entry{ID: "urn:test:1"})
+	assert.Equal(t, "entry urn:test:1 has no link to a CAP alert", err.Error())
+}
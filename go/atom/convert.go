@@ -0,0 +1,143 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"strings"
+
+	"github.com/IBM/cap/go/cap"
+)
+
+// NewEntry populates an entry from the alert and its first info, the CAP
+// elements of the entry hold the areas of that info combined. The entry has
+// no link, Publisher links entries to where their alerts are published.
+func NewEntry(alert *cap.Alert) Entry {
+	entry := Entry{
+		ID:        alert.Identifier,
+		Updated:   alert.Sent,
+		Published: alert.Sent,
+		Status:    alert.Status,
+		MsgType:   alert.MsgType,
+		Author:    []Person{{Name: alert.Sender}},
+	}
+	if len(alert.Info) == 0 {
+		entry.Title = Text{Content: alert.Identifier}
+		return entry
+	}
+	info := &alert.Info[0]
+	entry.Title = Text{Content: firstNonEmpty(info.Headline, info.Event, alert.Identifier)}
+	entry.Summary = Text{Content: firstNonEmpty(info.Description, info.Headline)}
+	if info.SenderName != "" {
+		entry.Author[0].Name = info.SenderName
+	}
+	for _, category := range info.Category {
+		entry.Category = append(entry.Category, Category{Term: category})
+	}
+	entry.Event = info.Event
+	entry.Effective = info.Effective
+	entry.Expires = info.Expires
+	entry.Urgency = info.Urgency
+	entry.Severity = info.Severity
+	entry.Certainty = info.Certainty
+	entry.Parameter = append(entry.Parameter, info.Parameter...)
+	var areaDescs, names []string
+	geocodes := make(map[string][]string)
+	for _, area := range info.Area {
+		if area.AreaDesc != "" {
+			areaDescs = append(areaDescs, area.AreaDesc)
+		}
+		entry.Polygon = append(entry.Polygon, area.Polygon...)
+		entry.Circle = append(entry.Circle, area.Circle...)
+		for _, geocode := range area.Geocode {
+			geocodes[geocode.ValueName] = append(geocodes[geocode.ValueName], geocode.Value)
+			if len(geocodes[geocode.ValueName]) == 1 {
+				names = append(names, geocode.ValueName)
+			}
+		}
+	}
+	// like the NWS feed, the values of each geocode name are space delimited
+	for _, name := range names {
+		entry.Geocode.Add(name, strings.Join(geocodes[name], " "))
+	}
+	entry.AreaDesc = strings.Join(areaDescs, "; ")
+	return entry
+}
+
+// ToAlert returns the partial alert summarized by the entry, e.g. for when the
+// full alert cannot be retrieved. The alert has a single info and area, its
+// identifier is the entry's ID and its sender the entry's author.
+func (e *Entry) ToAlert() *cap.Alert {
+	alert := &cap.Alert{
+		Identifier: e.ID,
+		Sent:       firstTime(e.Published, e.Updated),
+		Status:     e.Status,
+		MsgType:    e.MsgType,
+		Scope:      "Public",
+	}
+	if len(e.Author) > 0 {
+		alert.Sender = e.Author[0].Name
+	}
+	info := cap.Info{
+		Event:       e.Event,
+		Urgency:     e.Urgency,
+		Severity:    e.Severity,
+		Certainty:   e.Certainty,
+		Effective:   e.Effective,
+		Expires:     e.Expires,
+		Headline:    strings.TrimSpace(e.Title.Content),
+		Description: strings.TrimSpace(e.Summary.Content),
+		Parameter:   append([]NamedValue(nil), e.Parameter...),
+	}
+	for _, category := range e.Category {
+		// the NWS feed has cap:category elements rather than Atom categories
+		info.Category = append(info.Category, firstNonEmpty(category.Term, strings.TrimSpace(category.Content)))
+	}
+	if link, ok := e.FindLink(RelAlternate, ""); ok {
+		info.Web, _ = e.resolve(link)
+	}
+	area := cap.Area{AreaDesc: e.AreaDesc, Polygon: nonEmpty(e.Polygon), Circle: nonEmpty(e.Circle)}
+	for _, pair := range e.Geocode.Pairs {
+		// NWS geocode values are space delimited lists
+		for _, value := range strings.Fields(pair.Value) {
+			area.Geocode = append(area.Geocode, NamedValue{ValueName: pair.ValueName, Value: value})
+		}
+	}
+	info.Area = []cap.Area{area}
+	alert.Info = []cap.Info{info}
+	return alert
+}
+
+func firstTime(times ...TimeStr) TimeStr {
+	for _, t := range times {
+		if t != "" {
+			return t
+		}
+	}
+	return ""
+}
+
+// nonEmpty returns the values which are not blank, the NWS feed has empty
+// cap:polygon elements for alerts without a polygon
+func nonEmpty(values []string) []string {
+	var result []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
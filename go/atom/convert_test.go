@@ -0,0 +1,123 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/IBM/cap/go/cap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntryToAlert(t *testing.T) {
+	feed, err := getNwsAtomFeedExample()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := feed.Entries[0]
+	alert := entry.ToAlert()
+	assert.Equal(t, entry.ID, alert.Identifier)
+	assert.Equal(t, "w-nws.webmaster@noaa.gov", alert.Sender)
+	assert.Equal(t, TimeStr("2018-08-15T14:52:00-08:00"), alert.Sent)
+	assert.Equal(t, "Actual", alert.Status)
+	assert.Equal(t, "Alert", alert.MsgType)
+
+	info := alert.Info[0]
+	assert.Equal(t, []string{"Met"}, info.Category)
+	assert.Equal(t, "High Wind Warning", info.Event)
+	assert.Equal(t, "Expected", info.Urgency)
+	assert.Equal(t, "Severe", info.Severity)
+	assert.Equal(t, "Likely", info.Certainty)
+	assert.Equal(t, TimeStr("2018-08-16T07:00:00-08:00"), info.Expires)
+	assert.Equal(t, entry.Title.Content, info.Headline)
+	assert.Equal(t, entry.Link[0].Href, info.Web)
+	assert.Equal(t, "/O.CON.PAFG.HW.W.0011.180816T0000Z-180816T1500Z/", info.GetParameter("VTEC"))
+
+	area := info.Area[0]
+	assert.Equal(t, "Eastern Beaufort Sea Coast", area.AreaDesc)
+	// the empty cap:polygon is dropped
+	assert.Nil(t, area.Polygon)
+	assert.Equal(t, []string{"002185"}, area.GetGeocodes("FIPS6"))
+	assert.Equal(t, []string{"AKZ204"}, area.GetGeocodes("UGC"))
+}
+
+func TestEntryToAlertSplitsGeocodeLists(t *testing.T) {
+	feed, err := getNwsAtomFeedExample()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range feed.Entries {
+		alert := entry.ToAlert()
+		for _, pair := range entry.Geocode.Pairs {
+			values := alert.Info[0].Area[0].GetGeocodes(pair.ValueName)
+			assert.Equal(t, len(strings.Fields(pair.Value)), len(values), entry.ID)
+		}
+	}
+}
+
+func TestNewEntry(t *testing.T) {
+	alert := getAmberAlertExample(t)
+	entry := NewEntry(alert)
+	info := alert.Info[0]
+	assert.Equal(t, alert.Identifier, entry.ID)
+	assert.Equal(t, alert.Sent, entry.Updated)
+	assert.Equal(t, alert.Sent, entry.Published)
+	assert.Equal(t, info.SenderName, entry.Author[0].Name)
+	assert.Equal(t, info.Headline, entry.Title.Content)
+	assert.Equal(t, info.Description, entry.Summary.Content)
+	assert.Equal(t, "Rescue", entry.Category[0].Term)
+	assert.Equal(t, info.Event, entry.Event)
+	assert.Equal(t, info.Urgency, entry.Urgency)
+	assert.Equal(t, info.Severity, entry.Severity)
+	assert.Equal(t, info.Certainty, entry.Certainty)
+	assert.Equal(t, "Los Angeles County", entry.AreaDesc)
+	assert.Equal(t, []NamedValue{{ValueName: "SAME", Value: "006037"}}, entry.Geocode.Pairs)
+	assert.Nil(t, entry.Link)
+}
+
+func TestNewEntryGroupsGeocodes(t *testing.T) {
+	alert := &cap.Alert{Identifier: "urn:test:1", Info: []cap.Info{{Area: []cap.Area{
+		{AreaDesc: "Travis", Geocode: []cap.NamedValue{{ValueName: "UGC", Value: "TXC453"}, {ValueName: "SAME", Value: "048453"}}},
+		{AreaDesc: "Williamson", Geocode: []cap.NamedValue{{ValueName: "UGC", Value: "TXC491"}, {ValueName: "SAME", Value: "048491"}}},
+	}}}}
+	entry := NewEntry(alert)
+	assert.Equal(t, "Travis; Williamson", entry.AreaDesc)
+	assert.Equal(t, []NamedValue{
+		{ValueName: "UGC", Value: "TXC453 TXC491"},
+		{ValueName: "SAME", Value: "048453 048491"},
+	}, entry.Geocode.Pairs)
+	// the identifier is the fallback title
+	assert.Equal(t, "urn:test:1", entry.Title.Content)
+}
+
+func TestNewEntryRoundTripsNWSEntries(t *testing.T) {
+	feed, err := getNwsAtomFeedExample()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range feed.Entries {
+		converted := NewEntry(entry.ToAlert())
+		assert.Equal(t, entry.ID, converted.ID)
+		assert.Equal(t, entry.Title.Content, converted.Title.Content)
+		assert.Equal(t, entry.Event, converted.Event)
+		assert.Equal(t, entry.Expires, converted.Expires)
+		assert.Equal(t, entry.Severity, converted.Severity)
+		assert.Equal(t, entry.AreaDesc, converted.AreaDesc)
+		assert.Equal(t, entry.Parameter, converted.Parameter)
+	}
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/IBM/cap/go/cap"
+)
+
+// ErrNoEmbeddedAlert is returned by EmbeddedAlert when the content of the
+// entry is not an inline CAP alert
+var ErrNoEmbeddedAlert = errors.New("entry has no embedded CAP alert")
+
+// EmbeddedAlert decodes the CAP alert inline in the entry's content, as
+// allowed by CAP-feeds, along with the alert's XML. Alerts of any supported
+// CAP version are decoded whether the content is XML, such as content of type
+// application/cap+xml, or XML escaped in text or html content. ErrNoEmbeddedAlert
+// is returned when the content does not contain an alert.
+func (e *Entry) EmbeddedAlert() (*cap.VersionedAlert, []byte, error) {
+	content := &e.Content
+	if content.Src != "" {
+		return nil, nil, ErrNoEmbeddedAlert
+	}
+	alert, raw, err := decodeEmbeddedAlert(content.Body)
+	if err != ErrNoEmbeddedAlert {
+		return alert, raw, err
+	}
+	switch content.Type {
+	case "", "text", "html":
+		return decodeEscapedAlert(content.Content)
+	}
+	return nil, nil, ErrNoEmbeddedAlert
+}
+
+// decodeEscapedAlert decodes the CAP alert escaped in text or html content,
+// which is any other text, including html which is not well formed XML, when
+// its root element is not a CAP alert. The error decoding an alert which is
+// not valid is returned.
+func decodeEscapedAlert(data string) (*cap.VersionedAlert, []byte, error) {
+	if !strings.HasPrefix(strings.TrimSpace(data), "<") {
+		return nil, nil, ErrNoEmbeddedAlert
+	}
+	if _, err := cap.DetectVersion([]byte(data)); err != nil {
+		return nil, nil, ErrNoEmbeddedAlert
+	}
+	return decodeEmbeddedAlert(data)
+}
+
+// decodeEmbeddedAlert decodes the first CAP alert element in data, which may
+// be nested, e.g. in the div of xhtml content
+func decodeEmbeddedAlert(data string) (*cap.VersionedAlert, []byte, error) {
+	decoder := xml.NewDecoder(strings.NewReader(data))
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, nil, ErrNoEmbeddedAlert
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "alert" {
+			continue
+		}
+		version, ok := cap.VersionOf(start.Name.Space)
+		if !ok {
+			continue
+		}
+		alert := cap.VersionedAlert{Version: version}
+		switch version {
+		case cap.Version10:
+			var alert10 cap.Alert10
+			err = decoder.DecodeElement(&alert10, &start)
+			alert.Alert = alert10.Alert
+		case cap.Version11:
+			var alert11 cap.Alert11
+			err = decoder.DecodeElement(&alert11, &start)
+			alert.Alert = alert11.Alert
+		default:
+			err = decoder.DecodeElement(&alert.Alert, &start)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		return &alert, []byte(data[offset:decoder.InputOffset()]), nil
+	}
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"html"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/IBM/cap/go/cap"
+	"github.com/stretchr/testify/assert"
+)
+
+// embeddedFeed returns a feed whose single entry has content as its content element
+func embeddedFeed(t *testing.T, content string) *Feed {
+	data := `<feed xmlns="http://www.w3.org/2005/Atom"><entry><id>urn:test:1</id>` + content + `</entry></feed>`
+	var feed Feed
+	if err := xml.Unmarshal([]byte(data), &feed); err != nil {
+		t.Fatal(err)
+	}
+	return &feed
+}
+
+// getAmberAlertXML returns the example alert without its XML declaration
+func getAmberAlertXML(t *testing.T, namespace string) string {
+	data, err := ioutil.ReadFile("../../resources/cap_amber_alert_example.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = bytes.Replace(data, []byte(cap.Namespace12), []byte(namespace), 1)
+	if i := bytes.Index(data, []byte("?>")); i >= 0 {
+		data = data[i+2:]
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func TestEntryEmbeddedAlertDecodesInlineAlert(t *testing.T) {
+	alertXML := getAmberAlertXML(t, cap.Namespace11)
+	feed := embeddedFeed(t, `<content type="application/cap+xml">`+alertXML+`</content>`)
+	alert, raw, err := feed.Entries[0].EmbeddedAlert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, cap.Version11, alert.Version)
+	assert.Equal(t, "KAR0-0306112239-SW", alert.Identifier)
+	// the raw alert is a standalone document of the same alert
+	parsed, err := cap.ParseVersionedAlert(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, alert, parsed)
+}
+
+func TestEntryEmbeddedAlertResolvesPrefixDeclaredOnFeed(t *testing.T) {
+	data := `<feed xmlns="http://www.w3.org/2005/Atom" xmlns:cap="urn:oasis:names:tc:emergency:cap:1.2">` +
+		`<entry><id>urn:test:1</id><content type="application/cap+xml">` +
+		`<cap:alert><cap:identifier>KAR0-0306112239-SW</cap:identifier><cap:info><cap:event>Child Abduction</cap:event></cap:info></cap:alert>` +
+		`</content></entry></feed>`
+	var feed Feed
+	if err := xml.Unmarshal([]byte(data), &feed); err != nil {
+		t.Fatal(err)
+	}
+	alert, raw, err := feed.Entries[0].EmbeddedAlert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, cap.Version12, alert.Version)
+	assert.Equal(t, "Child Abduction", alert.Info[0].Event)
+	// the prefix is replaced by a declaration of the namespace
+	assert.Equal(t, `<alert xmlns="urn:oasis:names:tc:emergency:cap:1.2"><identifier>KAR0-0306112239-SW</identifier>`+
+		`<info><event>Child Abduction</event></info></alert>`, string(raw))
+	parsed, err := cap.ParseVersionedAlert(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, alert, parsed)
+}
+
+func TestEntryEmbeddedAlertDecodesNestedAndEscapedAlerts(t *testing.T) {
+	alertXML := getAmberAlertXML(t, cap.Namespace12)
+	feed := embeddedFeed(t, `<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml">`+alertXML+`</div></content>`)
+	alert, _, err := feed.Entries[0].EmbeddedAlert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, cap.Version12, alert.Version)
+
+	feed = embeddedFeed(t, `<content type="text">`+html.EscapeString(alertXML)+`</content>`)
+	alert, raw, err := feed.Entries[0].EmbeddedAlert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "KAR0-0306112239-SW", alert.Identifier)
+	assert.Equal(t, alertXML, string(raw))
+}
+
+func TestEntryEmbeddedAlertReturnsErrNoEmbeddedAlert(t *testing.T) {
+	for _, content := range []string{
+		``,
+		`<content type="application/cap+xml" src="https://alerts.example.com/1.xml"/>`,
+		`<content type="html">&lt;p&gt;Tornado warning&lt;/p&gt;</content>`,
+		// html which is not well formed XML
+		`<content type="html">&lt;p&gt;a&lt;br&gt;b&lt;/p&gt;</content>`,
+		`<content type="html">&lt;p&gt;&lt;alert xmlns="urn:oasis:names:tc:emergency:cap:1.2"/&gt;&lt;/p&gt;</content>`,
+		// escaped content is only read from text and html content
+		`<content type="application/octet-stream">&lt;alert xmlns="urn:oasis:names:tc:emergency:cap:1.2"/&gt;</content>`,
+		`<content type="application/xml"><alert xmlns="urn:example:alerts"/></content>`,
+	} {
+		_, _, err := embeddedFeed(t, content).Entries[0].EmbeddedAlert()
+		assert.Equal(t, ErrNoEmbeddedAlert, err, content)
+	}
+}
+
+func TestEntryEmbeddedAlertReturnsErrorOfEscapedAlert(t *testing.T) {
+	feed := embeddedFeed(t, `<content type="text">&lt;alert xmlns="urn:oasis:names:tc:emergency:cap:1.2"&gt;&lt;identifier&gt;</content>`)
+	_, _, err := feed.Entries[0].EmbeddedAlert()
+	assert.NotNil(t, err)
+	assert.NotEqual(t, ErrNoEmbeddedAlert, err)
+}
+
+func TestClientGetEntryAlertUsesEmbeddedAlert(t *testing.T) {
+	feed := embeddedFeed(t, `<content type="application/cap+xml">`+getAmberAlertXML(t, cap.Namespace12)+`</content>`)
+	// the client has no server, the alert must not be requested
+	client := &Client{BaseURL: "http://127.0.0.1:0/"}
+	alert, _, err := client.GetEntryAlert(context.Background(), &feed.Entries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "KAR0-0306112239-SW", alert.Identifier)
+
+	results := (&Fetcher{Client: client}).FetchAlerts(context.Background(), feed.Entries)
+	assert.Nil(t, results[0].Err)
+	assert.Equal(t, cap.Version12, results[0].Alert.Version)
+}
+
+func TestClientGetEntryAlertFollowsLinkOfHTMLEntry(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+	feed := embeddedFeed(t, `<link href="/alert" type="application/cap+xml"/>`+
+		`<content type="html">&lt;p&gt;Amber Alert&lt;br&gt;Los Angeles County&lt;/p&gt;</content>`)
+	client := &Client{BaseURL: server.URL}
+	alert, _, err := client.GetEntryAlert(context.Background(), &feed.Entries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "KAR0-0306112239-SW", alert.Identifier)
+
+	results := (&Fetcher{Client: client}).FetchAlerts(context.Background(), feed.Entries)
+	assert.Nil(t, results[0].Err)
+
+	for _, violation := range violationsOf(t, feed) {
+		assert.NotContains(t, violation.Message, "embedded CAP alert")
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// nwsFeedBaseURL - the NWS legacy CAP feeds, NwsNationalAtomFeedURL is us.php
+const nwsFeedBaseURL string = "https://alerts.weather.gov/cap/"
+
+var (
+	stateCodePattern = regexp.MustCompile(`^[A-Z]{2}$`)
+	ugcPattern       = regexp.MustCompile(`^[A-Z]{2}[CZ][0-9]{3}$`)
+)
+
+// UGC - a Universal Geographic Code identifying a county (e.g. TXC201) or a
+// forecast zone (e.g. TXZ213)
+type UGC string
+
+// ParseUGC parses a county or zone UGC, case insensitively
+func ParseUGC(s string) (UGC, error) {
+	code := strings.ToUpper(strings.TrimSpace(s))
+	if !ugcPattern.MatchString(code) {
+		return "", fmt.Errorf("invalid UGC %q, expected a county (e.g. TXC201) or zone (e.g. TXZ213) code", s)
+	}
+	return UGC(code), nil
+}
+
+// Valid - whether the UGC is a county or zone code as returned by ParseUGC
+func (u UGC) Valid() bool {
+	return ugcPattern.MatchString(string(u))
+}
+
+// State - the two letter state code of the UGC, "" when it is not valid
+func (u UGC) State() string {
+	if !u.Valid() {
+		return ""
+	}
+	return string(u[:2])
+}
+
+// IsCounty - whether the UGC identifies a county rather than a zone, false
+// when it is not valid
+func (u UGC) IsCounty() bool {
+	return u.Valid() && u[2] == 'C'
+}
+
+// FeedURL - the URL of the NWS Atom feed of the alerts for the county or zone,
+// "" when the UGC is not valid
+func (u UGC) FeedURL() string {
+	if !u.Valid() {
+		return ""
+	}
+	y := "0"
+	if u.IsCounty() {
+		y = "1"
+	}
+	return nwsFeedBaseURL + "wwaatmget.php?" + url.Values{"x": {string(u)}, "y": {y}}.Encode()
+}
+
+// StateFeedURL returns the URL of the NWS Atom feed of the alerts for a state
+// or territory, identified by its two letter code (e.g. TX)
+func StateFeedURL(state string) (string, error) {
+	code := strings.ToUpper(strings.TrimSpace(state))
+	if !stateCodePattern.MatchString(code) {
+		return "", fmt.Errorf("invalid state code %q, expected two letters (e.g. TX)", state)
+	}
+	return nwsFeedBaseURL + strings.ToLower(code) + ".php?x=1", nil
+}
+
+// ZoneFeedURL returns the URL of the NWS Atom feed of the alerts for a county
+// or zone UGC (e.g. TXC201 or TXZ213)
+func ZoneFeedURL(ugc string) (string, error) {
+	code, err := ParseUGC(ugc)
+	if err != nil {
+		return "", err
+	}
+	return code.FeedURL(), nil
+}
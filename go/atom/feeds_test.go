@@ -0,0 +1,71 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateFeedURL(t *testing.T) {
+	feedURL, err := StateFeedURL("tx")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://alerts.weather.gov/cap/tx.php?x=1", feedURL)
+
+	for _, state := range []string{"", "T", "TEX", "T1"} {
+		_, err := StateFeedURL(state)
+		assert.Error(t, err, state)
+	}
+}
+
+func TestZoneFeedURL(t *testing.T) {
+	feedURL, err := ZoneFeedURL("TXZ213")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://alerts.weather.gov/cap/wwaatmget.php?x=TXZ213&y=0", feedURL)
+
+	feedURL, err = ZoneFeedURL(" txc201 ")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://alerts.weather.gov/cap/wwaatmget.php?x=TXC201&y=1", feedURL)
+
+	_, err = ZoneFeedURL("TXX201")
+	assert.Equal(t, `invalid UGC "TXX201", expected a county (e.g. TXC201) or zone (e.g. TXZ213) code`, err.Error())
+}
+
+func TestParseUGC(t *testing.T) {
+	ugc, err := ParseUGC("akz204")
+	assert.Nil(t, err)
+	assert.Equal(t, UGC("AKZ204"), ugc)
+	assert.Equal(t, "AK", ugc.State())
+	assert.False(t, ugc.IsCounty())
+	assert.True(t, UGC("TXC201").IsCounty())
+
+	for _, code := range []string{"", "AKZ20", "AKZ2041", "A1Z204"} {
+		_, err := ParseUGC(code)
+		assert.Error(t, err, code)
+	}
+}
+
+func TestInvalidUGC(t *testing.T) {
+	for _, ugc := range []UGC{"", "T", "TXX201", "txc201"} {
+		assert.False(t, ugc.Valid(), string(ugc))
+		assert.Equal(t, "", ugc.State(), string(ugc))
+		assert.False(t, ugc.IsCounty(), string(ugc))
+		assert.Equal(t, "", ugc.FeedURL(), string(ugc))
+	}
+	assert.True(t, UGC("TXC201").Valid())
+}
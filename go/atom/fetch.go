@@ -0,0 +1,167 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/IBM/cap/go/cap"
+)
+
+// Fetcher defaults, chosen to stay well within what the NWS tolerates
+const (
+	DefaultFetchWorkers int     = 4
+	DefaultFetchRate    float64 = 10
+)
+
+// FetchResult - the outcome of retrieving the alert of a single entry
+type FetchResult struct {
+	Entry *Entry
+	Alert *cap.VersionedAlert
+	Raw   []byte
+	Err   error
+}
+
+// Fetcher - retrieves the alerts of many entries concurrently, with a bounded
+// number of workers and a limit on the request rate
+type Fetcher struct {
+	Client  *Client // Client - used to retrieve the alerts, DefaultClient when nil
+	Workers int     // Workers - the maximum concurrent requests, DefaultFetchWorkers when zero
+	// Rate - the maximum requests per second, DefaultFetchRate when zero and
+	// unlimited when negative
+	Rate float64
+	// Burst - the number of requests which may be made at once before the
+	// Rate applies, 1 when zero
+	Burst int
+}
+
+// FetchAlerts retrieves the alert of each entry, the results are in the order
+// of the entries. A failure to retrieve one alert is reported in its result and
+// does not stop the others, entries not fetched before ctx is done have the
+// context's error.
+func (f *Fetcher) FetchAlerts(ctx context.Context, entries []Entry) []FetchResult {
+	results := make([]FetchResult, len(entries))
+	for i := range entries {
+		results[i].Entry = &entries[i]
+	}
+	limiter := newTokenBucket(f.rate(), f.Burst)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < f.workers(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				result := &results[i]
+				if alert, raw, err := result.Entry.EmbeddedAlert(); err != ErrNoEmbeddedAlert {
+					// embedded alerts need no request so are not rate limited
+					result.Alert, result.Raw, result.Err = alert, raw, err
+					continue
+				}
+				if result.Err = limiter.wait(ctx); result.Err != nil {
+					continue
+				}
+				result.Alert, result.Raw, result.Err = f.client().GetEntryAlert(ctx, result.Entry)
+			}
+		}()
+	}
+	for i := range entries {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// FetchAlerts retrieves the alerts of the entries with a default Fetcher
+// using the DefaultClient
+func FetchAlerts(ctx context.Context, entries []Entry) []FetchResult {
+	var f Fetcher
+	return f.FetchAlerts(ctx, entries)
+}
+
+func (f *Fetcher) client() *Client {
+	if f.Client == nil {
+		return DefaultClient
+	}
+	return f.Client
+}
+
+func (f *Fetcher) workers() int {
+	if f.Workers <= 0 {
+		return DefaultFetchWorkers
+	}
+	return f.Workers
+}
+
+func (f *Fetcher) rate() float64 {
+	if f.Rate == 0 {
+		return DefaultFetchRate
+	}
+	return f.Rate
+}
+
+// tokenBucket - a token bucket rate limiter, a bucket with a negative rate
+// does not limit
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // rate - tokens added per second
+	burst  float64 // burst - the capacity of the bucket
+	tokens float64 // tokens - may be negative when waiters have reserved tokens
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst <= 0 {
+		burst = 1
+	}
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// wait takes a token, waiting until one is available or ctx is done
+func (b *tokenBucket) wait(ctx context.Context) error {
+	if b.rate < 0 {
+		return ctx.Err()
+	}
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mu.Unlock()
+
+	if delay == 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetcherReturnsResultsInEntryOrder(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+	var mu sync.Mutex
+	active, maxActive := 0, 0
+	handler := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		handler.ServeHTTP(w, r)
+		mu.Lock()
+		active--
+		mu.Unlock()
+	})
+
+	var entries []Entry
+	for i := 0; i < 12; i++ {
+		href := server.URL + "/alert"
+		if i%4 == 3 {
+			href = server.URL + "/missing"
+		}
+		entries = append(entries, Entry{ID: fmt.Sprintf("urn:test:%d", i), Link: []Link{{Href: href}}})
+	}
+	fetcher := Fetcher{Workers: 3, Rate: -1}
+	results := fetcher.FetchAlerts(context.Background(), entries)
+
+	assert.Equal(t, len(entries), len(results))
+	for i, result := range results {
+		assert.Equal(t, &entries[i], result.Entry)
+		if i%4 == 3 {
+			assert.Nil(t, result.Alert)
+			assert.Equal(t, "HTTP status code: 404", result.Err.Error())
+		} else {
+			assert.Nil(t, result.Err)
+			assert.Equal(t, "KAR0-0306112239-SW", result.Alert.Identifier)
+			assert.NotEqual(t, 0, len(result.Raw))
+		}
+	}
+	assert.True(t, maxActive <= 3, "at most 3 concurrent requests, got %d", maxActive)
+}
+
+func TestFetcherLimitsRate(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+	entries := make([]Entry, 5)
+	for i := range entries {
+		entries[i] = Entry{ID: fmt.Sprintf("urn:test:%d", i), Link: []Link{{Href: server.URL + "/alert"}}}
+	}
+	// a burst of 2 then 3 more at 50 per second takes at least 60ms
+	fetcher := Fetcher{Workers: 5, Rate: 50, Burst: 2}
+	start := time.Now()
+	results := fetcher.FetchAlerts(context.Background(), entries)
+	elapsed := time.Since(start)
+	for _, result := range results {
+		assert.Nil(t, result.Err)
+	}
+	assert.True(t, elapsed >= 55*time.Millisecond, "took %v", elapsed)
+}
+
+func TestFetcherReportsContextErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	entries := []Entry{{ID: "urn:test:1", Link: []Link{{Href: "http://example.com/alert"}}}}
+	results := FetchAlerts(ctx, entries)
+	assert.Equal(t, context.Canceled, results[0].Err)
+}
+
+func TestTokenBucketWaits(t *testing.T) {
+	bucket := newTokenBucket(100, 1)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, bucket.wait(context.Background()))
+	}
+	assert.True(t, time.Since(start) >= 15*time.Millisecond)
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// Geocode names used by the NWS feed
+const (
+	GeocodeFIPS6 string = "FIPS6"
+	GeocodeUGC   string = "UGC"
+)
+
+// Geocode - as specified by the NWS Atom feed, a sequence of valueName and
+// value element pairs. The value of a pair may be a space delimited list of codes.
+type Geocode struct {
+	Pairs []NamedValue
+	// Warnings - the problems with the structure of the geocode found when it
+	// was decoded, e.g. a value without a valueName. Malformed pairs are dropped.
+	Warnings []string
+}
+
+// Add appends a pair
+func (g *Geocode) Add(name string, value string) {
+	g.Pairs = append(g.Pairs, NamedValue{ValueName: name, Value: value})
+}
+
+// GetGeocodes returns back an array of values for the Geocode element with the same name
+func (g *Geocode) GetGeocodes(name string) []string {
+	values := []string{}
+	for _, pair := range g.Pairs {
+		if pair.ValueName == name {
+			values = append(values, strings.Fields(pair.Value)...)
+		}
+	}
+	return values
+}
+
+// Map returns the codes of each name
+func (g *Geocode) Map() map[string][]string {
+	m := make(map[string][]string)
+	for _, pair := range g.Pairs {
+		m[pair.ValueName] = append(m[pair.ValueName], strings.Fields(pair.Value)...)
+	}
+	return m
+}
+
+// FIPS6 returns the FIPS6 county codes, e.g. 002185
+func (g *Geocode) FIPS6() []string {
+	return g.GetGeocodes(GeocodeFIPS6)
+}
+
+// UGC returns the UGC county and zone codes, e.g. AKZ204
+func (g *Geocode) UGC() []string {
+	return g.GetGeocodes(GeocodeUGC)
+}
+
+// UnmarshalXML decodes the valueName and value elements into pairs, recording
+// a warning for each element which does not fit the structure
+func (g *Geocode) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	*g = Geocode{}
+	var name string
+	pending := false // pending - whether name is waiting for its value
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "valueName" && t.Name.Local != "value" {
+				g.warnf("unexpected element <%s>", t.Name.Local)
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			var text string
+			if err := d.DecodeElement(&text, &t); err != nil {
+				return err
+			}
+			text = strings.TrimSpace(text)
+			switch {
+			case t.Name.Local == "valueName" && pending:
+				g.warnf("valueName %q has no value", name)
+				name = text
+			case t.Name.Local == "valueName":
+				name, pending = text, true
+			case !pending:
+				g.warnf("value %q has no valueName", text)
+			case name == "":
+				g.warnf("value %q has an empty valueName", text)
+				pending = false
+			default:
+				g.Add(name, text)
+				pending = false
+			}
+		case xml.EndElement:
+			if pending {
+				g.warnf("valueName %q has no value", name)
+			}
+			return nil
+		}
+	}
+}
+
+// MarshalXML writes the pairs, an empty geocode is omitted
+func (g Geocode) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if len(g.Pairs) == 0 {
+		return nil
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, pair := range g.Pairs {
+		if err := e.EncodeElement(pair.ValueName, xml.StartElement{Name: xml.Name{Local: "valueName"}}); err != nil {
+			return err
+		}
+		if err := e.EncodeElement(pair.Value, xml.StartElement{Name: xml.Name{Local: "value"}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+func (g *Geocode) warnf(format string, args ...interface{}) {
+	g.Warnings = append(g.Warnings, fmt.Sprintf(format, args...))
+}
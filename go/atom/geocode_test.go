@@ -0,0 +1,95 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeocodeAccessors(t *testing.T) {
+	feed, err := getNwsAtomFeedExample()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range feed.Entries {
+		assert.Nil(t, entry.Geocode.Warnings, entry.ID)
+	}
+	geocode := feed.Entries[0].Geocode
+	assert.Equal(t, []string{"002185"}, geocode.FIPS6())
+	assert.Equal(t, []string{"AKZ204"}, geocode.UGC())
+	assert.Equal(t, map[string][]string{"FIPS6": {"002185"}, "UGC": {"AKZ204"}}, geocode.Map())
+}
+
+func TestGeocodeSplitsAndCombinesValues(t *testing.T) {
+	var geocode Geocode
+	geocode.Add("UGC", "TXC453 TXC491")
+	geocode.Add("UGC", " TXZ192 ")
+	assert.Equal(t, []string{"TXC453", "TXC491", "TXZ192"}, geocode.UGC())
+	assert.Equal(t, []string{}, geocode.FIPS6())
+}
+
+func TestGeocodeUnmarshalWarnsOnMalformedPairs(t *testing.T) {
+	xmlData := []byte(`<geocode>
+<valueName>FIPS6</valueName>
+<value>002185</value>
+<value>002188</value>
+<valueName>UGC</valueName>
+<valueName>SAME</valueName>
+<value>002185</value>
+<extra>x</extra>
+<valueName></valueName>
+<value>AKZ204</value>
+<valueName>UGC</valueName>
+</geocode>`)
+	var geocode Geocode
+	if err := xml.Unmarshal(xmlData, &geocode); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []NamedValue{
+		{ValueName: "FIPS6", Value: "002185"},
+		{ValueName: "SAME", Value: "002185"},
+	}, geocode.Pairs)
+	assert.Equal(t, []string{
+		`value "002188" has no valueName`,
+		`valueName "UGC" has no value`,
+		`unexpected element <extra>`,
+		`value "AKZ204" has an empty valueName`,
+		`valueName "UGC" has no value`,
+	}, geocode.Warnings)
+}
+
+func TestGeocodeMarshalsPairs(t *testing.T) {
+	var geocode Geocode
+	geocode.Add("FIPS6", "002185")
+	geocode.Add("UGC", "AKZ204")
+	xmlData, err := xml.Marshal(struct {
+		XMLName xml.Name `xml:"entry"`
+		Geocode Geocode  `xml:"geocode"`
+	}{Geocode: geocode})
+	assert.Nil(t, err)
+	assert.Equal(t, `<entry><geocode><valueName>FIPS6</valueName><value>002185</value><valueName>UGC</valueName><value>AKZ204</value></geocode></entry>`, string(xmlData))
+
+	xmlData, err = xml.Marshal(struct {
+		XMLName xml.Name `xml:"entry"`
+		Geocode Geocode  `xml:"geocode"`
+	}{})
+	assert.Nil(t, err)
+	assert.Equal(t, `<entry></entry>`, string(xmlData))
+}
@@ -0,0 +1,223 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// Link relations defined by RFC 4287
+const (
+	RelAlternate string = "alternate"
+	RelRelated   string = "related"
+	RelSelf      string = "self"
+	RelEnclosure string = "enclosure"
+	RelVia       string = "via"
+)
+
+// Relation returns the link relation, which is alternate when rel is absent
+func (l *Link) Relation() string {
+	if l.Rel == "" {
+		return RelAlternate
+	}
+	return l.Rel
+}
+
+// MediaType returns the lower case media type of the link without parameters,
+// or "" when the link has no type
+func (l *Link) MediaType() string {
+	return baseMediaType(l.Type)
+}
+
+// URL returns the href of the link resolved against its xml:base
+func (l *Link) URL() (string, error) {
+	if l.Base == "" {
+		return l.Href, nil
+	}
+	return resolveReference(l.Base, l.Href)
+}
+
+// LinksByRel returns the links of the entry with the relation
+func (e *Entry) LinksByRel(rel string) []Link {
+	var links []Link
+	for _, link := range e.Link {
+		if link.Relation() == rel {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// FindLink returns the first link of the entry with the relation and media
+// type, an empty rel or mediaType matches any link
+func (e *Entry) FindLink(rel string, mediaType string) (*Link, bool) {
+	return findLink(e.Link, rel, mediaType)
+}
+
+// FindLink returns the first link of the feed with the relation and media
+// type, an empty rel or mediaType matches any link
+func (f *Feed) FindLink(rel string, mediaType string) (*Link, bool) {
+	return findLink(f.Link, rel, mediaType)
+}
+
+func findLink(links []Link, rel string, mediaType string) (*Link, bool) {
+	mediaType = baseMediaType(mediaType)
+	for i := range links {
+		link := &links[i]
+		if rel != "" && link.Relation() != rel {
+			continue
+		}
+		if mediaType != "" && link.MediaType() != mediaType {
+			continue
+		}
+		return link, true
+	}
+	return nil, false
+}
+
+// AlertLink returns the link of the entry to its CAP alert. In order of
+// preference this is a link of type application/cap+xml, a link of an XML
+// type or, as in the NWS feed, an alternate link which is not typed as HTML.
+func (e *Entry) AlertLink() (*Link, bool) {
+	if link, ok := e.FindLink("", CAPMediaType); ok {
+		return link, true
+	}
+	for i := range e.Link {
+		if isXMLMediaType(e.Link[i].MediaType()) {
+			return &e.Link[i], true
+		}
+	}
+	for i := range e.Link {
+		link := &e.Link[i]
+		if link.Relation() == RelAlternate && !isHTMLMediaType(link.MediaType()) {
+			return link, true
+		}
+	}
+	return nil, false
+}
+
+// AlertURL returns the URL of the entry's CAP alert, resolved against the
+// xml:base of the entry and its link
+func (e *Entry) AlertURL() (string, error) {
+	link, ok := e.AlertLink()
+	if !ok {
+		return "", fmt.Errorf("entry %s has no link to a CAP alert", e.ID)
+	}
+	return e.resolve(link)
+}
+
+// resolve returns the href of the link resolved against the xml:base of the
+// link, which is itself relative to the xml:base of the entry
+func (e *Entry) resolve(link *Link) (string, error) {
+	base := e.Base
+	if link.Base != "" {
+		if base == "" {
+			base = link.Base
+		} else {
+			var err error
+			if base, err = resolveReference(base, link.Base); err != nil {
+				return "", err
+			}
+		}
+	}
+	if base == "" {
+		return link.Href, nil
+	}
+	return resolveReference(base, link.Href)
+}
+
+// ResolveLinks makes the links of the feed and its entries absolute by
+// resolving them against documentURL, the URL the feed was retrieved from,
+// and the xml:base attributes inherited from the feed by its entries. The
+// effective xml:base is recorded in the Base of the feed and each entry, so an
+// entry taken from the feed still resolves relative references. References
+// which cannot be resolved are left unchanged.
+func (f *Feed) ResolveLinks(documentURL string) {
+	base := inheritBase(documentURL, f.Base)
+	f.Base = base
+	resolveLinks(base, f.Link)
+	for i := range f.Entries {
+		entry := &f.Entries[i]
+		entry.Base = inheritBase(base, entry.Base)
+		resolveLinks(entry.Base, entry.Link)
+		if entry.Content.Src != "" && entry.Base != "" {
+			if src, err := resolveReference(entry.Base, entry.Content.Src); err == nil {
+				entry.Content.Src = src
+			}
+		}
+	}
+}
+
+// inheritBase returns the xml:base of an element whose parent has the
+// effective xml:base parent
+func inheritBase(parent string, base string) string {
+	if parent == "" {
+		return base
+	}
+	if base == "" {
+		return parent
+	}
+	resolved, err := resolveReference(parent, base)
+	if err != nil {
+		return base
+	}
+	return resolved
+}
+
+// resolveLinks makes the links absolute, the xml:base of each link is
+// resolved against base and cleared
+func resolveLinks(base string, links []Link) {
+	for i := range links {
+		link := &links[i]
+		linkBase := inheritBase(base, link.Base)
+		if linkBase == "" {
+			continue
+		}
+		if href, err := resolveReference(linkBase, link.Href); err == nil {
+			link.Href = href
+			link.Base = ""
+		}
+	}
+}
+
+// baseMediaType returns the lower case media type without parameters
+func baseMediaType(mediaType string) string {
+	if mediaType == "" {
+		return ""
+	}
+	parsed, _, err := mime.ParseMediaType(mediaType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(mediaType))
+	}
+	return parsed
+}
+
+// isXMLMediaType - whether the media type is XML, excluding Atom and XHTML
+func isXMLMediaType(mediaType string) bool {
+	switch mediaType {
+	case "application/xml", "text/xml":
+		return true
+	}
+	return false
+}
+
+// isHTMLMediaType - whether the media type is an HTML web page
+func isHTMLMediaType(mediaType string) bool {
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
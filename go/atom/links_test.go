@@ -0,0 +1,130 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntryAlertLinkPrefersCAPMediaType(t *testing.T) {
+	entry := Entry{ID: "urn:test:1", Link: []Link{
+		{Href: "https://alerts.example.com/1.html", Type: "text/html"},
+		{Href: "https://alerts.example.com/1", Rel: RelRelated},
+		{Href: "https://alerts.example.com/1.xml", Rel: RelAlternate, Type: "application/CAP+xml; charset=utf-8"},
+	}}
+	link, ok := entry.AlertLink()
+	assert.True(t, ok)
+	assert.Equal(t, "https://alerts.example.com/1.xml", link.Href)
+
+	link, ok = entry.FindLink(RelAlternate, "text/html")
+	assert.True(t, ok)
+	assert.Equal(t, "https://alerts.example.com/1.html", link.Href)
+	_, ok = entry.FindLink(RelSelf, "")
+	assert.False(t, ok)
+	assert.Equal(t, 2, len(entry.LinksByRel(RelAlternate)))
+}
+
+func TestEntryAlertLinkFallsBack(t *testing.T) {
+	entry := Entry{Link: []Link{
+		{Href: "https://alerts.example.com/1.html", Type: "text/html"},
+		{Href: "https://alerts.example.com/1.xml", Rel: RelRelated, Type: "application/xml"},
+	}}
+	link, _ := entry.AlertLink()
+	assert.Equal(t, "https://alerts.example.com/1.xml", link.Href)
+
+	// like the NWS feed, an untyped alternate link to the alert
+	entry = Entry{Link: []Link{
+		{Href: "https://alerts.example.com/1.html", Type: "text/html"},
+		{Href: "https://alerts.example.com/1"},
+	}}
+	link, _ = entry.AlertLink()
+	assert.Equal(t, "https://alerts.example.com/1", link.Href)
+
+	entry = Entry{ID: "urn:test:1", Link: []Link{{Href: "https://alerts.example.com/1.html", Type: "text/html"}}}
+	_, ok := entry.AlertLink()
+	assert.False(t, ok)
+	_, err := entry.AlertURL()
+	assert.Equal(t, "entry urn:test:1 has no link to a CAP alert", err.Error())
+}
+
+func TestFeedResolveLinksInheritsBase(t *testing.T) {
+	data := []byte(`<feed xmlns="http://www.w3.org/2005/Atom" xml:base="/cap/">
+  <link rel="self" href="feed.xml"/>
+  <entry>
+    <id>urn:test:1</id>
+    <link href="alerts/1.xml" type="application/cap+xml"/>
+  </entry>
+  <entry xml:base="https://mirror.example.com/cap/">
+    <id>urn:test:2</id>
+    <link xml:base="archive/" href="2.xml" type="application/cap+xml"/>
+  </entry>
+</feed>`)
+	var feed Feed
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		t.Fatal(err)
+	}
+	feed.ResolveLinks("https://alerts.example.com/feeds/all")
+	assert.Equal(t, "https://alerts.example.com/cap/feed.xml", feed.Link[0].Href)
+
+	alertURL, err := feed.Entries[0].AlertURL()
+	assert.Nil(t, err)
+	assert.Equal(t, "https://alerts.example.com/cap/alerts/1.xml", alertURL)
+	alertURL, err = feed.Entries[1].AlertURL()
+	assert.Nil(t, err)
+	assert.Equal(t, "https://mirror.example.com/cap/archive/2.xml", alertURL)
+	assert.Equal(t, "", feed.Entries[1].Link[0].Base)
+}
+
+func TestEntryAlertURLResolvesUnresolvedBase(t *testing.T) {
+	entry := Entry{
+		CommonAttributes: CommonAttributes{Base: "https://alerts.example.com/cap/"},
+		Link:             []Link{{CommonAttributes: CommonAttributes{Base: "2018/"}, Href: "1.xml", Type: CAPMediaType}},
+	}
+	alertURL, err := entry.AlertURL()
+	assert.Nil(t, err)
+	assert.Equal(t, "https://alerts.example.com/cap/2018/1.xml", alertURL)
+}
+
+func TestClientGetEntryAlertFollowsRelativeCAPLink(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+	handler := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/feeds/relative" {
+			w.Write([]byte(`<feed xmlns="http://www.w3.org/2005/Atom"><entry><id>urn:test:1</id>
+<link href="/page.html" type="text/html"/><link href="../alert" type="application/cap+xml"/></entry></feed>`))
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+
+	client := &Client{}
+	feed, _, err := client.GetFeed(context.Background(), server.URL+"/feeds/relative")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alert, _, err := client.GetEntryAlert(context.Background(), &feed.Entries[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "KAR0-0306112239-SW", alert.Identifier)
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"bytes"
+	"encoding/xml"
+
+	"github.com/IBM/cap/go/cap"
+)
+
+// Namespace is the Atom XML namespace
+const Namespace string = "http://www.w3.org/2005/Atom"
+
+// CAPNamespace is the namespace of the cap: elements written in marshaled
+// entries, the CAP-feeds recommendations index CAP 1.2 alerts
+const CAPNamespace string = cap.Namespace12
+
+// Marshal returns the feed as an indented XML document
+func (f *Feed) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(f); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+	return buf.Bytes(), nil
+}
+
+// entryXML - Entry with the tags used for marshaling: the CAP elements are
+// written with the cap prefix, which MarshalXML declares on the entry, and
+// empty optional elements are omitted
+type entryXML struct {
+	XMLName xml.Name `xml:"http://www.w3.org/2005/Atom entry"`
+	CommonAttributes
+	ID          string       `xml:"id"`
+	Title       Text         `xml:"title"`
+	Updated     TimeStr      `xml:"updated"`
+	Author      []Person     `xml:"author,omitempty"`
+	Content     Text         `xml:"content,omitempty"`
+	Link        []Link       `xml:"link,omitempty"`
+	Summary     Text         `xml:"summary"`
+	Category    []Category   `xml:"category,omitempty"`
+	Contributor []Person     `xml:"contributor,omitempty"`
+	Published   TimeStr      `xml:"published,omitempty"`
+	Rights      Text         `xml:"rights,omitempty"`
+	Source      []Source     `xml:"source,omitempty"`
+	Event       string       `xml:"cap:event,omitempty"`
+	Effective   TimeStr      `xml:"cap:effective,omitempty"`
+	Expires     TimeStr      `xml:"cap:expires,omitempty"`
+	Status      string       `xml:"cap:status,omitempty"`
+	MsgType     string       `xml:"cap:msgType,omitempty"`
+	Urgency     string       `xml:"cap:urgency,omitempty"`
+	Severity    string       `xml:"cap:severity,omitempty"`
+	Certainty   string       `xml:"cap:certainty,omitempty"`
+	AreaDesc    string       `xml:"cap:areaDesc,omitempty"`
+	Polygon     []string     `xml:"cap:polygon,omitempty"`
+	Circle      []string     `xml:"cap:circle,omitempty"`
+	Geocode     Geocode      `xml:"cap:geocode,omitempty"`
+	Parameter   []NamedValue `xml:"cap:parameter,omitempty"`
+	Extension   []Extension  `xml:",any,omitempty"`
+}
+
+// MarshalXML writes the entry, declaring the cap prefix of its CAP elements
+func (entry Entry) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:cap"}, Value: CAPNamespace})
+	return e.EncodeElement(entryXML(entry), start)
+}
+
+// textXML - Text with only one of the chardata and innerxml fields, encoding/xml
+// would write both
+type textXML struct {
+	CommonAttributes
+	Type    string `xml:"type,attr,omitempty"`
+	Src     string `xml:"src,attr,omitempty"`
+	Content string `xml:",chardata"`
+	Body    string `xml:",innerxml"`
+}
+
+// MarshalXML writes the text, Body is written as is for xhtml text or when
+// there is no Content. Text without content or src is omitted.
+func (t Text) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if t.Content == "" && t.Body == "" && t.Src == "" {
+		return nil
+	}
+	x := textXML{CommonAttributes: t.CommonAttributes, Type: t.Type, Src: t.Src}
+	if t.Content == "" || (t.Type == "xhtml" && t.Body != "") {
+		x.Body = t.Body
+	} else {
+		x.Content = t.Content
+	}
+	return e.EncodeElement(x, start)
+}
+
+// xmlNamespace - the namespace of the xml: attributes, e.g. xml:base
+const xmlNamespace string = "http://www.w3.org/XML/1998/namespace"
+
+// UnmarshalXML decodes the text. Like an extension, Body is the XML content
+// re-encoded so that it is self-contained: namespace prefixes declared on
+// ancestor elements, such as a cap prefix declared on the feed, are replaced
+// by default namespace declarations on the elements using them.
+func (t *Text) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	*t = Text{}
+	for _, attr := range start.Attr {
+		switch {
+		case attr.Name.Space == xmlNamespace && attr.Name.Local == "base":
+			t.Base = attr.Value
+		case attr.Name.Space == xmlNamespace && attr.Name.Local == "lang":
+			t.Lang = attr.Value
+		case attr.Name.Space == "" && attr.Name.Local == "type":
+			t.Type = attr.Value
+		case attr.Name.Space == "" && attr.Name.Local == "src":
+			t.Src = attr.Value
+		}
+	}
+	var content, body bytes.Buffer
+	e := xml.NewEncoder(&body)
+	// open - the names of the open elements as decoded, children in the same
+	// namespace as their parent inherit its declaration
+	var open []xml.Name
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch tok := token.(type) {
+		case xml.StartElement:
+			open = append(open, tok.Name)
+			tok.Name = inheritedName(open)
+			tok.Attr = withoutNamespaceDecls(tok.Attr)
+			token = tok
+		case xml.EndElement:
+			if len(open) == 0 {
+				if err := e.Flush(); err != nil {
+					return err
+				}
+				t.Content = content.String()
+				t.Body = body.String()
+				return nil
+			}
+			tok.Name = inheritedName(open)
+			open = open[:len(open)-1]
+			token = tok
+		case xml.CharData:
+			if len(open) == 0 {
+				content.Write(tok)
+			}
+		}
+		if err := e.EncodeToken(xml.CopyToken(token)); err != nil {
+			return err
+		}
+	}
+}
+
+// UnmarshalXML decodes the category, recording its namespace
+func (c *Category) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain Category
+	var x plain
+	if err := d.DecodeElement(&x, &start); err != nil {
+		return err
+	}
+	*c = Category(x)
+	c.Space = start.Name.Space
+	return nil
+}
+
+// inheritedName returns the name of the innermost open element to encode,
+// without its namespace when that is the namespace of its parent
+func inheritedName(open []xml.Name) xml.Name {
+	name := open[len(open)-1]
+	if len(open) > 1 && open[len(open)-2].Space == name.Space {
+		name.Space = ""
+	}
+	return name
+}
+
+func withoutNamespaceDecls(attrs []xml.Attr) []xml.Attr {
+	var found []xml.Attr
+	for _, attr := range attrs {
+		if attr.Name.Space != "xmlns" && !(attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			found = append(found, attr)
+		}
+	}
+	return found
+}
+
+// MarshalXMLAttr writes the text's content as an attribute, e.g. a link title
+func (t Text) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	if t.Content == "" {
+		return xml.Attr{}, nil
+	}
+	return xml.Attr{Name: name, Value: t.Content}, nil
+}
+
+// UnmarshalXMLAttr reads an attribute into the text's content
+func (t *Text) UnmarshalXMLAttr(attr xml.Attr) error {
+	t.Content = attr.Value
+	return nil
+}
+
+// MarshalXML omits generators without content
+func (g Generator) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if g == (Generator{}) {
+		return nil
+	}
+	type generator Generator // without the MarshalXML method
+	return e.EncodeElement(generator(g), start)
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// RFC 5005 link relations of paged and archived feeds
+const (
+	RelNext        string = "next"
+	RelPrevArchive string = "prev-archive"
+)
+
+// MaxFeedPages is the most feed documents WalkFeed retrieves
+const MaxFeedPages int = 1000
+
+// ErrFeedLoop is returned by WalkFeed when a feed links back to a document
+// which was already retrieved
+var ErrFeedLoop = errors.New("feed pages link in a loop")
+
+// ErrStopWalk can be returned by the fn passed to WalkFeed to stop walking
+var ErrStopWalk = errors.New("stop walking the feed")
+
+// WalkFeed retrieves the feed at feedURL, the NWS national feed when empty, and
+// then each feed document linked
+// from the previous one by a next link (a paged feed) or else a prev-archive
+// link (an archived feed), as specified by RFC 5005, calling fn for each
+// document. Walking stops without error at a document with neither link or
+// when fn returns ErrStopWalk, and with ErrFeedLoop when a link leads back to
+// an already retrieved document.
+func (c *Client) WalkFeed(ctx context.Context, feedURL string, fn func(feed *Feed) error) error {
+	visited := make(map[string]bool)
+	for pageURL := c.defaultFeed(feedURL); pageURL != ""; {
+		if visited[pageURL] {
+			return ErrFeedLoop
+		}
+		if len(visited) == MaxFeedPages {
+			return fmt.Errorf("feed has more than %d pages", MaxFeedPages)
+		}
+		visited[pageURL] = true
+		feed, _, err := c.GetFeed(ctx, pageURL)
+		if err != nil {
+			return err
+		}
+		if err := fn(feed); err != nil {
+			if err == ErrStopWalk {
+				return nil
+			}
+			return err
+		}
+		link, ok := feed.FindLink(RelNext, "")
+		if !ok {
+			link, ok = feed.FindLink(RelPrevArchive, "")
+		}
+		if !ok {
+			break
+		}
+		if pageURL, err = link.URL(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAllEntries walks the paged or archived feed at feedURL and returns the
+// entries of all its documents. An entry which appears in several documents is
+// returned once, at its first position, with its most recently updated
+// version. When the pages link in a loop the entries retrieved so far are
+// returned with ErrFeedLoop.
+func (c *Client) GetAllEntries(ctx context.Context, feedURL string) ([]Entry, error) {
+	var entries []Entry
+	index := make(map[string]int)
+	err := c.WalkFeed(ctx, feedURL, func(feed *Feed) error {
+		for _, entry := range feed.Entries {
+			i, ok := index[entry.ID]
+			if !ok {
+				index[entry.ID] = len(entries)
+				entries = append(entries, entry)
+			} else if newer(entry.Updated, entries[i].Updated) {
+				entries[i] = entry
+			}
+		}
+		return nil
+	})
+	if err != nil && err != ErrFeedLoop {
+		return nil, err
+	}
+	return entries, err
+}
+
+// newer - whether a is a later time than b, unparsable times are never newer
+func newer(a TimeStr, b TimeStr) bool {
+	ta, err := TimeParse(a)
+	if err != nil {
+		return false
+	}
+	tb, err := TimeParse(b)
+	return err != nil || ta.After(tb)
+}
+
+// resolveReference resolves the possibly relative href against base
+func resolveReference(base string, href string) (string, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return "", err
+	}
+	return b.ResolveReference(ref).String(), nil
+}
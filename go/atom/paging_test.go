@@ -0,0 +1,144 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newPagedServer serves the feeds by path, recording the paths requested
+func newPagedServer(t *testing.T, feeds map[string]Feed, requested *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requested = append(*requested, r.URL.Path)
+		feed, ok := feeds[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		xmlData, err := feed.Marshal()
+		if err != nil {
+			t.Error(err)
+		}
+		w.Write(xmlData)
+	}))
+}
+
+func TestGetAllEntriesFollowsArchives(t *testing.T) {
+	feeds := map[string]Feed{
+		"/current": {ID: "urn:feed", Link: []Link{{Href: "archive/2", Rel: RelPrevArchive}}, Entries: []Entry{
+			{ID: "urn:entry:4", Updated: "2018-08-15T12:00:00-00:00"},
+			{ID: "urn:entry:3", Updated: "2018-08-15T11:00:00-00:00"},
+		}},
+		"/archive/2": {ID: "urn:feed", Link: []Link{{Href: "/archive/1", Rel: RelPrevArchive}}, Entries: []Entry{
+			{ID: "urn:entry:3", Updated: "2018-08-15T10:00:00-00:00"},
+			{ID: "urn:entry:2", Updated: "2018-08-14T10:00:00-00:00"},
+		}},
+		"/archive/1": {ID: "urn:feed", Entries: []Entry{
+			// a later version in an older archive
+			{ID: "urn:entry:2", Updated: "2018-08-14T11:00:00-00:00", Title: Text{Content: "corrected"}},
+			{ID: "urn:entry:1", Updated: "2018-08-13T10:00:00-00:00"},
+		}},
+	}
+	var requested []string
+	server := newPagedServer(t, feeds, &requested)
+	defer server.Close()
+
+	entries, err := NewClient().GetAllEntries(context.Background(), server.URL+"/current")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"/current", "/archive/2", "/archive/1"}, requested)
+	var ids []string
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	assert.Equal(t, []string{"urn:entry:4", "urn:entry:3", "urn:entry:2", "urn:entry:1"}, ids)
+	assert.Equal(t, TimeStr("2018-08-15T11:00:00-00:00"), entries[1].Updated)
+	assert.Equal(t, "corrected", entries[2].Title.Content)
+}
+
+func TestWalkFeedFollowsNextPages(t *testing.T) {
+	feeds := map[string]Feed{
+		"/feed/1": {ID: "urn:feed", Link: []Link{{Href: "/feed/1", Rel: "self"}, {Href: "2", Rel: RelNext}}},
+		"/feed/2": {ID: "urn:feed", Link: []Link{{Href: "/feed/2", Rel: "self"}}},
+	}
+	var requested []string
+	server := newPagedServer(t, feeds, &requested)
+	defer server.Close()
+	pages := 0
+	err := NewClient().WalkFeed(context.Background(), server.URL+"/feed/1", func(feed *Feed) error {
+		pages++
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, pages)
+	// the relative next link is resolved against the page
+	assert.Equal(t, []string{"/feed/1", "/feed/2"}, requested)
+}
+
+func TestWalkFeedDetectsLoops(t *testing.T) {
+	feeds := map[string]Feed{
+		"/a": {ID: "urn:feed", Link: []Link{{Href: "/b", Rel: RelNext}}, Entries: []Entry{{ID: "urn:entry:a"}}},
+		"/b": {ID: "urn:feed", Link: []Link{{Href: "/a", Rel: RelNext}}, Entries: []Entry{{ID: "urn:entry:b"}}},
+	}
+	var requested []string
+	server := newPagedServer(t, feeds, &requested)
+	defer server.Close()
+	entries, err := NewClient().GetAllEntries(context.Background(), server.URL+"/a")
+	assert.Equal(t, ErrFeedLoop, err)
+	assert.Equal(t, 2, len(entries))
+	assert.Equal(t, []string{"/a", "/b"}, requested)
+}
+
+func TestWalkFeedStops(t *testing.T) {
+	feeds := map[string]Feed{
+		"/a": {ID: "urn:feed", Link: []Link{{Href: "/b", Rel: RelNext}}},
+		"/b": {ID: "urn:feed", Link: []Link{{Href: "/missing", Rel: RelNext}}},
+	}
+	var requested []string
+	server := newPagedServer(t, feeds, &requested)
+	defer server.Close()
+	err := NewClient().WalkFeed(context.Background(), server.URL+"/a", func(feed *Feed) error {
+		return ErrStopWalk
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/a"}, requested)
+
+	_, err = NewClient().GetAllEntries(context.Background(), server.URL+"/a")
+	assert.Equal(t, "HTTP status code: 404", err.Error())
+}
+
+func TestWalkFeedDefaultsToNationalFeed(t *testing.T) {
+	feeds := map[string]Feed{
+		"/cap/us.php": {ID: "urn:feed", Link: []Link{{Href: "/older", Rel: RelPrevArchive}}},
+		"/older":      {ID: "urn:feed"},
+	}
+	var requested []string
+	server := newPagedServer(t, feeds, &requested)
+	defer server.Close()
+	client := &Client{BaseURL: server.URL}
+	err := client.WalkFeed(context.Background(), "", func(feed *Feed) error {
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/cap/us.php", "/older"}, requested)
+}
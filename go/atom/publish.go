@@ -0,0 +1,102 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/IBM/cap/go/cap"
+)
+
+// CAPMediaType is the media type of links to CAP alerts
+const CAPMediaType string = "application/cap+xml"
+
+// Publisher - builds Atom index feeds of CAP alerts following the OASIS
+// CAP-feeds recommendations
+type Publisher struct {
+	ID      string // ID - the feed's universally unique and permanent URI
+	Title   string // Title - the feed's human readable title
+	Author  Person // Author - the feed's author, entries without a sender name are attributed to the alert's sender
+	URL     string // URL - if set, the web page presenting the alerts, added as the alternate link
+	SelfURL string // SelfURL - if set, the URL where the feed is published, added as the self link
+	// AlertURL - returns the absolute URL where the alert is published, each
+	// entry links to its alert
+	AlertURL func(alert *cap.Alert) string
+}
+
+// Feed builds a feed with an entry for each alert, in the order of the alerts.
+// The feed is updated at the latest sent time of the alerts, or now when
+// there are none.
+func (p *Publisher) Feed(alerts []*cap.Alert) (*Feed, error) {
+	if p.AlertURL == nil {
+		return nil, errors.New("publisher has no AlertURL")
+	}
+	if p.Author.Name == "" {
+		// atom:author requires a name
+		return nil, errors.New("publisher has no Author name")
+	}
+	feed := &Feed{
+		ID:     p.ID,
+		Title:  Text{Content: p.Title},
+		Author: []Person{p.Author},
+	}
+	if p.URL != "" {
+		feed.Link = append(feed.Link, Link{Href: p.URL, Rel: RelAlternate, Type: "text/html"})
+	}
+	if p.SelfURL != "" {
+		feed.Link = append(feed.Link, Link{Href: p.SelfURL, Rel: "self", Type: "application/atom+xml"})
+	}
+	var updated time.Time
+	for _, alert := range alerts {
+		href := p.AlertURL(alert)
+		if href == "" {
+			return nil, fmt.Errorf("alert %s has no URL", alert.Identifier)
+		}
+		entry := NewEntry(alert)
+		if !absoluteURI(entry.ID) {
+			// the entry id must be an IRI, the alert's URL is as permanent
+			entry.ID = href
+		}
+		entry.Link = []Link{{Href: href, Type: CAPMediaType}}
+		feed.Entries = append(feed.Entries, entry)
+		if sent, err := cap.TimeParse(alert.Sent); err == nil && sent.After(updated) {
+			updated = sent
+		}
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	feed.Updated = Time(updated)
+	return feed, nil
+}
+
+func absoluteURI(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.IsAbs()
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
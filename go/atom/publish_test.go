@@ -0,0 +1,187 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/IBM/cap/go/cap"
+	"github.com/stretchr/testify/assert"
+)
+
+func getAmberAlertExample(t *testing.T) *cap.Alert {
+	xmlData, err := ioutil.ReadFile("../../resources/cap_amber_alert_example.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alert, err := cap.ParseAlert(xmlData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return alert
+}
+
+func testPublisher() *Publisher {
+	return &Publisher{
+		ID:      "https://alerts.example.com/cap/",
+		Title:   "Example alerts",
+		Author:  Person{Name: "alerts@example.com"},
+		SelfURL: "https://alerts.example.com/cap/feed.xml",
+		AlertURL: func(alert *cap.Alert) string {
+			return "https://alerts.example.com/cap/" + alert.Identifier + ".xml"
+		},
+	}
+}
+
+func TestPublisherFeed(t *testing.T) {
+	alert := getAmberAlertExample(t)
+	feed, err := testPublisher().Feed([]*cap.Alert{alert})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "https://alerts.example.com/cap/", feed.ID)
+	assert.Equal(t, alert.Sent, feed.Updated)
+	// no alternate link without a URL, the ID is not necessarily one
+	assert.Equal(t, 1, len(feed.Link))
+	assert.Equal(t, "self", feed.Link[0].Rel)
+	assert.Equal(t, 1, len(feed.Entries))
+
+	entry := feed.Entries[0]
+	info := alert.Info[0]
+	// the identifier is not a URI
+	assert.Equal(t, "https://alerts.example.com/cap/KAR0-0306112239-SW.xml", entry.ID)
+	assert.Equal(t, info.Headline, entry.Title.Content)
+	assert.Equal(t, CAPMediaType, entry.Link[0].Type)
+	assert.Equal(t, entry.ID, entry.Link[0].Href)
+	assert.Equal(t, info.Event, entry.Event)
+	assert.Equal(t, info.Urgency, entry.Urgency)
+	assert.Equal(t, info.Severity, entry.Severity)
+	assert.Equal(t, info.Expires, entry.Expires)
+	assert.Equal(t, info.Area[0].AreaDesc, entry.AreaDesc)
+}
+
+func TestPublisherFeedRequiresAlertURL(t *testing.T) {
+	_, err := (&Publisher{ID: "https://alerts.example.com/cap/"}).Feed(nil)
+	assert.Error(t, err)
+}
+
+func TestPublisherFeedRequiresAuthorName(t *testing.T) {
+	publisher := testPublisher()
+	publisher.Author = Person{}
+	_, err := publisher.Feed(nil)
+	assert.Equal(t, "publisher has no Author name", err.Error())
+}
+
+func TestPublisherFeedLinksURL(t *testing.T) {
+	publisher := testPublisher()
+	publisher.ID = "urn:uuid:60a76c80-d399-11d9-b93C-0003939e0af6"
+	publisher.URL = "https://alerts.example.com/"
+	feed, err := publisher.Feed(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, RelAlternate, feed.Link[0].Relation())
+	assert.Equal(t, "https://alerts.example.com/", feed.Link[0].Href)
+	assert.Equal(t, "self", feed.Link[1].Rel)
+}
+
+func TestFeedMarshal(t *testing.T) {
+	alert := getAmberAlertExample(t)
+	feed, err := testPublisher().Feed([]*cap.Alert{alert})
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed.Entries[0].Link[0].Title = Text{Content: "CAP alert"}
+	xmlData, err := feed.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := string(xmlData)
+	assert.True(t, strings.HasPrefix(doc, xml.Header))
+	assert.Contains(t, doc, `<feed xmlns="http://www.w3.org/2005/Atom">`)
+	assert.Contains(t, doc, `xmlns:cap="urn:oasis:names:tc:emergency:cap:1.2"`)
+	assert.Contains(t, doc, `<cap:event>`+alert.Info[0].Event+`</cap:event>`)
+	assert.Contains(t, doc, `<cap:severity>`+alert.Info[0].Severity+`</cap:severity>`)
+	assert.Contains(t, doc, `type="application/cap+xml"`)
+	assert.Contains(t, doc, `title="CAP alert"`)
+	// empty optional elements are omitted
+	assert.NotContains(t, doc, "<generator>")
+	assert.NotContains(t, doc, "<content>")
+	assert.Contains(t, doc, "<cap:geocode>\n      <valueName>SAME</valueName>\n      <value>006037</value>")
+
+	// the marshaled feed parses to the same feed, with the cap elements in
+	// the CAP namespace
+	var parsed Feed
+	if err := xml.Unmarshal(xmlData, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	entry := parsed.Entries[0]
+	assert.Equal(t, feed.Entries[0].ID, entry.ID)
+	assert.Equal(t, feed.Entries[0].Title.Content, entry.Title.Content)
+	assert.Equal(t, "CAP alert", entry.Link[0].Title.Content)
+	assert.Equal(t, alert.Info[0].Urgency, entry.Urgency)
+	assert.Equal(t, alert.Info[0].Expires, entry.Expires)
+	var capElements struct {
+		Entry struct {
+			Event string `xml:"urn:oasis:names:tc:emergency:cap:1.2 event"`
+		} `xml:"http://www.w3.org/2005/Atom entry"`
+	}
+	if err := xml.Unmarshal(xmlData, &capElements); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, alert.Info[0].Event, capElements.Entry.Event)
+}
+
+func TestFeedMarshalRoundTripsNWSFeed(t *testing.T) {
+	feed, err := getNwsAtomFeedExample()
+	if err != nil {
+		t.Fatal(err)
+	}
+	xmlData, err := feed.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed Feed
+	if err := xml.Unmarshal(xmlData, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(feed.Entries), len(parsed.Entries))
+	assert.Equal(t, feed.Generator.Content, parsed.Generator.Content)
+	for i := range feed.Entries {
+		assert.Equal(t, feed.Entries[i].ID, parsed.Entries[i].ID)
+		assert.Equal(t, feed.Entries[i].Title.Content, parsed.Entries[i].Title.Content)
+		assert.Equal(t, feed.Entries[i].Event, parsed.Entries[i].Event)
+		assert.Equal(t, feed.Entries[i].Geocode, parsed.Entries[i].Geocode)
+		assert.Equal(t, feed.Entries[i].Parameter, parsed.Entries[i].Parameter)
+	}
+}
+
+func TestTextMarshalsXHTMLBody(t *testing.T) {
+	text := Text{Type: "xhtml", Content: "bold", Body: `<div xmlns="http://www.w3.org/1999/xhtml"><b>bold</b></div>`}
+	xmlData, err := xml.Marshal(Entry{ID: "urn:test:1", Summary: text})
+	assert.Nil(t, err)
+	assert.Contains(t, string(xmlData), `<summary type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><b>bold</b></div></summary>`)
+
+	xmlData, err = xml.Marshal(Entry{ID: "urn:test:1", Summary: Text{Content: "a < b"}, Content: Text{Base: "https://example.com/", Src: "alert.xml", Type: CAPMediaType}})
+	assert.Nil(t, err)
+	assert.Contains(t, string(xmlData), `<summary>a &lt; b</summary>`)
+	assert.Contains(t, string(xmlData), `xml:base="https://example.com/"`)
+	assert.Contains(t, string(xmlData), `src="alert.xml"`)
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxBodySize is the largest response body accepted by clients without
+// a MaxBodySize, the NWS national feed is a few megabytes
+const DefaultMaxBodySize int64 = 32 << 20
+
+// acceptEncoding is sent with every request, the client decodes the response
+const acceptEncoding string = "gzip, deflate"
+
+// ErrNoContent is returned when a response has an empty body
+var ErrNoContent = errors.New("No content")
+
+// BodyTooLargeError - returned when a response body, once decoded, is larger
+// than the client's MaxBodySize
+type BodyTooLargeError struct {
+	URL   string
+	Limit int64
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("response body of %s exceeds the limit of %d bytes", e.URL, e.Limit)
+}
+
+// ContentTypeError - returned when a response is not of an XML media type,
+// e.g. an HTML error page served with a 200 OK
+type ContentTypeError struct {
+	URL         string
+	ContentType string
+}
+
+func (e *ContentTypeError) Error() string {
+	return fmt.Sprintf("unexpected content type %q of %s, expected an XML media type", e.ContentType, e.URL)
+}
+
+// ContentEncodingError - returned when a response has an unsupported
+// Content-Encoding or its body cannot be decoded
+type ContentEncodingError struct {
+	URL      string
+	Encoding string
+	Err      error // Err - the decoding error, nil when the encoding is unsupported
+}
+
+func (e *ContentEncodingError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("unsupported content encoding %q of %s", e.Encoding, e.URL)
+	}
+	return fmt.Sprintf("cannot decode %s content of %s: %v", e.Encoding, e.URL, e.Err)
+}
+
+// genericMediaTypes - sent by servers which do not know the type of a file,
+// including Go servers sniffing XML without a declaration
+var genericMediaTypes = map[string]bool{"": true, "text/plain": true, "application/octet-stream": true}
+
+// checkContentType returns a *ContentTypeError unless the response is of an
+// XML media type, such as application/atom+xml, or a generic one
+func checkContentType(r *http.Response) error {
+	contentType := r.Header.Get("Content-Type")
+	mediaType := baseMediaType(contentType)
+	if genericMediaTypes[mediaType] || isXMLMediaType(mediaType) || (strings.HasSuffix(mediaType, "+xml") && !isHTMLMediaType(mediaType)) {
+		return nil
+	}
+	return &ContentTypeError{URL: responseURL(r), ContentType: contentType}
+}
+
+// readBody reads the body of the response, decoding its Content-Encoding,
+// with a *BodyTooLargeError when the decoded body is larger than limit. A
+// negative limit is unlimited.
+func readBody(r *http.Response, limit int64) ([]byte, error) {
+	if r.Body == nil {
+		return nil, ErrNoContent
+	}
+	if limit >= 0 && r.ContentLength > limit && isIdentity(r.Header.Get("Content-Encoding")) {
+		// rejected before reading, compressed bodies are checked once decoded
+		return nil, &BodyTooLargeError{URL: responseURL(r), Limit: limit}
+	}
+	body, err := decodeBody(r)
+	if err != nil {
+		return nil, err
+	}
+	if limit >= 0 {
+		body = io.LimitReader(body, limit+1)
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		// a truncated body is left to be retried, as without an encoding
+		if encoding := r.Header.Get("Content-Encoding"); err != io.ErrUnexpectedEOF && !isIdentity(encoding) {
+			return nil, &ContentEncodingError{URL: responseURL(r), Encoding: encoding, Err: err}
+		}
+		return nil, err
+	}
+	if limit >= 0 && int64(len(data)) > limit {
+		return nil, &BodyTooLargeError{URL: responseURL(r), Limit: limit}
+	}
+	if len(data) == 0 {
+		return nil, ErrNoContent
+	}
+	return data, nil
+}
+
+// decodeBody returns a reader of the body with its content codings, which
+// are listed in the order they were applied, removed
+func decodeBody(r *http.Response) (io.Reader, error) {
+	var body io.Reader = r.Body
+	codings := strings.Split(r.Header.Get("Content-Encoding"), ",")
+	for i := len(codings) - 1; i >= 0; i-- {
+		coding := strings.ToLower(strings.TrimSpace(codings[i]))
+		var err error
+		switch coding {
+		case "", "identity":
+			continue
+		case "gzip", "x-gzip":
+			body, err = gzip.NewReader(body)
+		case "deflate":
+			body, err = newDeflateReader(body)
+		default:
+			return nil, &ContentEncodingError{URL: responseURL(r), Encoding: coding}
+		}
+		if err == io.EOF {
+			// an empty body is reported as no content
+			return strings.NewReader(""), nil
+		}
+		if err != nil {
+			return nil, &ContentEncodingError{URL: responseURL(r), Encoding: coding, Err: err}
+		}
+	}
+	return body, nil
+}
+
+// newDeflateReader reads deflate content, which should be zlib wrapped but is
+// sent raw by some servers
+func newDeflateReader(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	header, err := buffered.Peek(2)
+	if len(header) == 0 {
+		return nil, err
+	}
+	if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+	return flate.NewReader(buffered), nil
+}
+
+func isIdentity(encoding string) bool {
+	for _, coding := range strings.Split(encoding, ",") {
+		if coding = strings.ToLower(strings.TrimSpace(coding)); coding != "" && coding != "identity" {
+			return false
+		}
+	}
+	return true
+}
+
+func responseURL(r *http.Response) string {
+	if r.Request != nil && r.Request.URL != nil {
+		return r.Request.URL.String()
+	}
+	return ""
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const responseFeed = `<feed xmlns="http://www.w3.org/2005/Atom"><title>Test</title></feed>`
+
+func compress(t *testing.T, encoding string, data string) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw deflate":
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w = fw
+	}
+	w.Write([]byte(data))
+	w.Close()
+	return buf.Bytes()
+}
+
+// newResponseServer serves responseFeed at /identity, compressed at /gzip,
+// /deflate and /raw-deflate, chunked at /chunked and as the paths describe at
+// the other paths
+func newResponseServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/atom+xml")
+		switch r.URL.Path {
+		case "/identity":
+			w.Write([]byte(responseFeed))
+		case "/gzip":
+			assert.Equal(t, "gzip, deflate", r.Header.Get("Accept-Encoding"))
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(compress(t, "gzip", responseFeed))
+		case "/deflate":
+			w.Header().Set("Content-Encoding", "deflate")
+			w.Write(compress(t, "deflate", responseFeed))
+		case "/raw-deflate":
+			w.Header().Set("Content-Encoding", "deflate")
+			w.Write(compress(t, "raw deflate", responseFeed))
+		case "/chunked":
+			// flushing before the end sends the body chunked without a length
+			w.Write([]byte(responseFeed[:10]))
+			w.(http.Flusher).Flush()
+			w.Write([]byte(responseFeed[10:]))
+		case "/empty-chunked":
+			w.(http.Flusher).Flush()
+		case "/corrupt-gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write([]byte(responseFeed))
+		case "/brotli":
+			w.Header().Set("Content-Encoding", "br")
+			w.Write([]byte(responseFeed))
+		case "/html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html><body>Maintenance</body></html>"))
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"error": "maintenance"}`))
+		}
+	}))
+}
+
+func TestClientDecodesResponses(t *testing.T) {
+	server := newResponseServer(t)
+	defer server.Close()
+	client := &Client{BaseURL: server.URL}
+	for _, path := range []string{"/identity", "/gzip", "/deflate", "/raw-deflate", "/chunked"} {
+		feed, body, err := client.GetFeed(context.Background(), path)
+		if !assert.Nil(t, err, path) {
+			continue
+		}
+		assert.Equal(t, responseFeed, string(body), path)
+		assert.Equal(t, "Test", feed.Title.Content, path)
+	}
+}
+
+func TestClientRejectsEmptyResponses(t *testing.T) {
+	server := newResponseServer(t)
+	defer server.Close()
+	client := &Client{BaseURL: server.URL}
+	_, _, err := client.GetFeed(context.Background(), "/empty-chunked")
+	assert.Equal(t, ErrNoContent, err)
+}
+
+func TestClientLimitsBodySize(t *testing.T) {
+	server := newResponseServer(t)
+	defer server.Close()
+	client := &Client{BaseURL: server.URL, MaxBodySize: int64(len(responseFeed) - 1)}
+	// by the Content-Length, the length of the chunked body and the length
+	// of the decoded body
+	for _, path := range []string{"/identity", "/chunked", "/gzip"} {
+		_, _, err := client.GetFeed(context.Background(), path)
+		tooLarge, ok := err.(*BodyTooLargeError)
+		if assert.True(t, ok, path) {
+			assert.Equal(t, client.MaxBodySize, tooLarge.Limit)
+			assert.Equal(t, server.URL+path, tooLarge.URL)
+		}
+	}
+
+	client.MaxBodySize = int64(len(responseFeed))
+	_, _, err := client.GetFeed(context.Background(), "/gzip")
+	assert.Nil(t, err)
+
+	client.MaxBodySize = -1
+	_, _, err = client.GetFeed(context.Background(), "/chunked")
+	assert.Nil(t, err)
+}
+
+func TestClientRejectsUndecodableResponses(t *testing.T) {
+	server := newResponseServer(t)
+	defer server.Close()
+	client := &Client{BaseURL: server.URL}
+
+	_, _, err := client.GetFeed(context.Background(), "/brotli")
+	encodingErr, ok := err.(*ContentEncodingError)
+	if assert.True(t, ok) {
+		assert.Nil(t, encodingErr.Err)
+		assert.Equal(t, `unsupported content encoding "br" of `+server.URL+"/brotli", err.Error())
+	}
+
+	_, _, err = client.GetFeed(context.Background(), "/corrupt-gzip")
+	encodingErr, ok = err.(*ContentEncodingError)
+	if assert.True(t, ok) {
+		assert.Equal(t, "gzip", encodingErr.Encoding)
+		assert.NotNil(t, encodingErr.Err)
+	}
+}
+
+func TestClientVerifiesContentType(t *testing.T) {
+	server := newResponseServer(t)
+	defer server.Close()
+	client := &Client{BaseURL: server.URL}
+	for _, path := range []string{"/html", "/json"} {
+		_, _, err := client.GetFeed(context.Background(), path)
+		typeErr, ok := err.(*ContentTypeError)
+		if assert.True(t, ok, path) {
+			assert.Equal(t, server.URL+path, typeErr.URL)
+		}
+	}
+	_, _, err := client.GetFeed(context.Background(), "/html")
+	assert.True(t, strings.HasPrefix(err.Error(), `unexpected content type "text/html; charset=utf-8"`))
+}
+
+func TestCheckContentType(t *testing.T) {
+	for contentType, ok := range map[string]bool{
+		"":                          true,
+		"application/atom+xml":      true,
+		"application/cap+xml":       true,
+		"text/xml; charset=utf-8":   true,
+		"Application/XML":           true,
+		"text/plain; charset=utf-8": true,
+		"application/octet-stream":  true,
+		"text/html":                 false,
+		"application/xhtml+xml":     false,
+		"application/json":          false,
+		"image/png":                 false,
+		"application/geo+json; q=1": false,
+	} {
+		r := &http.Response{Header: http.Header{"Content-Type": {contentType}}}
+		assert.Equal(t, ok, checkContentType(r) == nil, contentType)
+	}
+}
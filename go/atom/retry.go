@@ -0,0 +1,165 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPError - returned when a server responds with a status other than 200 OK
+type HTTPError struct {
+	StatusCode int
+	// RetryAfter - the delay requested by the Retry-After header, zero when
+	// the response had none
+	RetryAfter time.Duration
+	URL        string
+	// Body - the start of the response body, which may describe the error,
+	// at most maxErrorBodySize bytes
+	Body []byte
+}
+
+// maxErrorBodySize - the most bytes of an error response kept by HTTPError
+const maxErrorBodySize int64 = 4096
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP status code: %d", e.StatusCode)
+}
+
+// Temporary - whether the request may succeed when retried, i.e. the server
+// responded with a 5xx or 429 Too Many Requests
+func (e *HTTPError) Temporary() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+}
+
+func newHTTPError(r *http.Response) *HTTPError {
+	e := &HTTPError{
+		StatusCode: r.StatusCode,
+		RetryAfter: parseRetryAfter(r.Header.Get("Retry-After"), time.Now()),
+	}
+	if r.Request != nil && r.Request.URL != nil {
+		e.URL = r.Request.URL.String()
+	}
+	if r.Body != nil {
+		if body, err := decodeBody(r); err == nil {
+			e.Body, _ = ioutil.ReadAll(io.LimitReader(body, maxErrorBodySize))
+		}
+	}
+	return e
+}
+
+// parseRetryAfter parses a Retry-After header, which is either a number of
+// seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// RetryPolicy - how a Client retries requests which failed with a 5xx, a 429
+// or a network error. The delay before retry n (counting from zero) is a random
+// duration between half and all of MinBackoff * 2^n, capped at MaxBackoff. A
+// Retry-After delay requested by the server is used instead when it is longer,
+// requests are not retried when it exceeds MaxBackoff.
+type RetryPolicy struct {
+	MaxRetries int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is the RetryPolicy of clients created by NewClient
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	MinBackoff: time.Second,
+	MaxBackoff: 30 * time.Second,
+}
+
+// backoff returns the delay before retry n after err, false when the request
+// should not be retried
+func (p *RetryPolicy) backoff(n int, err error) (time.Duration, bool) {
+	if p == nil || n >= p.MaxRetries {
+		return 0, false
+	}
+	delay := p.MinBackoff
+	for i := 0; i < n && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	if delay > 0 {
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+	}
+	if httpErr, ok := err.(*HTTPError); ok && httpErr.RetryAfter > delay {
+		if p.MaxBackoff > 0 && httpErr.RetryAfter > p.MaxBackoff {
+			return 0, false
+		}
+		delay = httpErr.RetryAfter
+	}
+	return delay, true
+}
+
+// transient - whether err is a failure which may not recur: a 5xx or 429
+// response, a network error or a truncated response body
+func transient(err error) bool {
+	switch e := err.(type) {
+	case *HTTPError:
+		return e.Temporary()
+	case *url.Error:
+		if e.Err == context.Canceled || e.Err == context.DeadlineExceeded {
+			return false
+		}
+		_, ok := e.Err.(net.Error)
+		return ok
+	case net.Error:
+		return true
+	}
+	return err == io.ErrUnexpectedEOF
+}
+
+// sleep waits for the delay, returning early with the context's error when
+// ctx is done
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
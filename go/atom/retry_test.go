@@ -0,0 +1,182 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyServer fails with the status until it has been requested failures times
+func flakyServer(t *testing.T, status int, failures int, requests *int) *httptest.Server {
+	server := newTestServer(t)
+	handler := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if *requests <= failures {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "unavailable", status)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+	return server
+}
+
+func testRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{MaxRetries: 3, MinBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+}
+
+func TestClientRetriesTransientFailures(t *testing.T) {
+	for _, status := range []int{http.StatusServiceUnavailable, http.StatusTooManyRequests} {
+		requests := 0
+		server := flakyServer(t, status, 2, &requests)
+		client := &Client{Retry: testRetryPolicy()}
+		alert, _, err := client.GetAlert(context.Background(), server.URL+"/alert")
+		server.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "KAR0-0306112239-SW", alert.Identifier)
+		assert.Equal(t, 3, requests)
+	}
+}
+
+func TestClientGivesUpAfterMaxRetries(t *testing.T) {
+	requests := 0
+	server := flakyServer(t, http.StatusBadGateway, 10, &requests)
+	defer server.Close()
+	client := &Client{Retry: testRetryPolicy()}
+	_, _, err := client.GetAlert(context.Background(), server.URL+"/alert")
+	httpErr, ok := err.(*HTTPError)
+	if !ok {
+		t.Fatalf("expected *HTTPError, got %v", err)
+	}
+	assert.Equal(t, http.StatusBadGateway, httpErr.StatusCode)
+	assert.Equal(t, server.URL+"/alert", httpErr.URL)
+	assert.Equal(t, 4, requests)
+}
+
+func TestClientDoesNotRetryClientErrors(t *testing.T) {
+	requests := 0
+	server := flakyServer(t, http.StatusForbidden, 10, &requests)
+	defer server.Close()
+	client := &Client{Retry: testRetryPolicy()}
+	_, _, err := client.GetAlert(context.Background(), server.URL+"/alert")
+	assert.Equal(t, "HTTP status code: 403", err.Error())
+	assert.Equal(t, 1, requests)
+}
+
+func TestClientRetriesNetworkErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close() // connections are refused
+	client := &Client{Retry: testRetryPolicy(), Breaker: NewCircuitBreaker(2, time.Hour)}
+	_, _, err := client.GetAlert(context.Background(), server.URL+"/alert")
+	assert.True(t, transient(err), "%v", err)
+	// the breaker opened after the second attempt
+	_, _, err = client.GetAlert(context.Background(), server.URL+"/alert")
+	assert.Equal(t, ErrCircuitOpen, err)
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 5, MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for n, max := range []time.Duration{100, 200, 400, 800, 1000} {
+		delay, ok := policy.backoff(n, errors.New("network"))
+		assert.True(t, ok)
+		assert.True(t, delay >= max*time.Millisecond/2 && delay <= max*time.Millisecond, "retry %d waits %v", n, delay)
+	}
+	_, ok := policy.backoff(5, errors.New("network"))
+	assert.False(t, ok)
+
+	delay, ok := policy.backoff(0, &HTTPError{StatusCode: 429, RetryAfter: 500 * time.Millisecond})
+	assert.True(t, ok)
+	assert.Equal(t, 500*time.Millisecond, delay)
+	_, ok = policy.backoff(0, &HTTPError{StatusCode: 429, RetryAfter: time.Minute})
+	assert.False(t, ok)
+
+	var none *RetryPolicy
+	_, ok = none.backoff(0, errors.New("network"))
+	assert.False(t, ok)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2018, 8, 15, 22, 57, 0, 0, time.UTC)
+	assert.Equal(t, 120*time.Second, parseRetryAfter("120", now))
+	assert.Equal(t, 30*time.Second, parseRetryAfter("Wed, 15 Aug 2018 22:57:30 GMT", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("Wed, 15 Aug 2018 22:56:30 GMT", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now))
+}
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	breaker := NewCircuitBreaker(2, 20*time.Millisecond)
+	breaker.record("a", true)
+	assert.Nil(t, breaker.allow("a"))
+	breaker.record("a", true)
+	assert.Equal(t, ErrCircuitOpen, breaker.allow("a"))
+	assert.True(t, breaker.Open("a"))
+	assert.Nil(t, breaker.allow("b"))
+
+	time.Sleep(25 * time.Millisecond)
+	// a single probe is let through
+	assert.Nil(t, breaker.allow("a"))
+	assert.Equal(t, ErrCircuitOpen, breaker.allow("a"))
+	breaker.record("a", true)
+	assert.Equal(t, ErrCircuitOpen, breaker.allow("a"))
+
+	time.Sleep(25 * time.Millisecond)
+	assert.Nil(t, breaker.allow("a"))
+	breaker.record("a", false)
+	assert.False(t, breaker.Open("a"))
+	assert.Nil(t, breaker.allow("a"))
+}
+
+func TestClientOnlyClosesCircuitOnSuccess(t *testing.T) {
+	requests := 0
+	server := flakyServer(t, http.StatusForbidden, 10, &requests)
+	defer server.Close()
+	client := &Client{Breaker: NewCircuitBreaker(2, time.Hour)}
+	host := server.Listener.Addr().String()
+	client.Breaker.record(host, true)
+
+	// neither a client error nor a canceled request is a success of the host
+	_, _, err := client.GetAlert(context.Background(), server.URL+"/alert")
+	assert.Equal(t, "HTTP status code: 403", err.Error())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = client.GetAlert(ctx, server.URL+"/alert")
+	assert.NotNil(t, err)
+	client.Breaker.record(host, true)
+	assert.True(t, client.Breaker.Open(host))
+}
+
+func TestCircuitBreakerReleasesProbe(t *testing.T) {
+	breaker := NewCircuitBreaker(1, 10*time.Millisecond)
+	breaker.record("a", true)
+	time.Sleep(15 * time.Millisecond)
+	assert.Nil(t, breaker.allow("a"))
+	assert.Equal(t, ErrCircuitOpen, breaker.allow("a"))
+	// a probe without an outcome lets another request probe the host
+	breaker.release("a")
+	assert.Nil(t, breaker.allow("a"))
+}
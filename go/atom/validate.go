@@ -0,0 +1,285 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/IBM/cap/go/cap"
+)
+
+// Specifications checked by Validate
+const (
+	SpecRFC4287  string = "RFC 4287"
+	SpecCAPFeeds string = "CAP-feeds"
+)
+
+// Violation - a constraint of a specification which a feed does not meet
+type Violation struct {
+	Path    string // Path - the offending element, e.g. feed/entry[2]/link[1]
+	Spec    string // Spec - SpecRFC4287 for requirements, SpecCAPFeeds for the OASIS CAP-feeds recommendations
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s (%s)", v.Path, v.Message, v.Spec)
+}
+
+// ValidationError - the violations found by Validate
+type ValidationError []Violation
+
+func (e ValidationError) Error() string {
+	messages := make([]string, len(e))
+	for i, violation := range e {
+		messages[i] = violation.String()
+	}
+	return fmt.Sprintf("%d violations: %s", len(e), strings.Join(messages, "; "))
+}
+
+// violations - collects the violations found by Validate
+type violations []Violation
+
+func (v *violations) add(path string, spec string, format string, args ...interface{}) {
+	*v = append(*v, Violation{Path: path, Spec: spec, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks the feed against the constraints of RFC 4287 and the OASIS
+// CAP-feeds recommendations, a ValidationError listing every violation is
+// returned when the feed does not meet them
+func (f *Feed) Validate() error {
+	var v violations
+	path := "feed"
+	v.checkIRI(path, f.Base)
+	v.checkID(path, f.ID)
+	v.checkText(path+"/title", &f.Title, true)
+	v.checkDate(path+"/updated", f.Updated, true)
+	v.checkText(path+"/subtitle", &f.SubTitle, false)
+	v.checkText(path+"/rights", &f.Rights, false)
+	v.checkPersons(path+"/author", f.Author)
+	v.checkPersons(path+"/contributor", f.Contributor)
+	v.checkLinks(path, f.Link)
+	v.checkCategories(path, f.Category)
+
+	ids := make(map[string]int)
+	for i := range f.Entries {
+		entry := &f.Entries[i]
+		entryPath := fmt.Sprintf("%s/entry[%d]", path, i+1)
+		if len(f.Author) == 0 && len(entry.Author) == 0 && !sourceHasAuthor(entry) {
+			v.add(entryPath, SpecRFC4287, "entry has no author and the feed has no author")
+		}
+		if first, ok := ids[entry.ID]; ok && entry.ID != "" {
+			v.add(entryPath+"/id", SpecRFC4287, "id %q is also the id of entry[%d]", entry.ID, first)
+		} else {
+			ids[entry.ID] = i + 1
+		}
+		v.checkEntry(entryPath, entry)
+	}
+	if len(v) == 0 {
+		return nil
+	}
+	return ValidationError(v)
+}
+
+func (v *violations) checkEntry(path string, entry *Entry) {
+	v.checkIRI(path, entry.Base)
+	v.checkID(path, entry.ID)
+	v.checkText(path+"/title", &entry.Title, true)
+	v.checkDate(path+"/updated", entry.Updated, true)
+	v.checkDate(path+"/published", entry.Published, false)
+	v.checkText(path+"/summary", &entry.Summary, false)
+	v.checkText(path+"/rights", &entry.Rights, false)
+	v.checkPersons(path+"/author", entry.Author)
+	v.checkPersons(path+"/contributor", entry.Contributor)
+	v.checkLinks(path, entry.Link)
+	v.checkCategories(path, entry.Category)
+	v.checkContent(path, entry)
+
+	// CAP-feeds recommendations
+	capLinks := 0
+	for i, link := range entry.Link {
+		if link.Type != CAPMediaType {
+			continue
+		}
+		capLinks++
+		if u, err := url.Parse(link.Href); err == nil && !u.IsAbs() {
+			v.add(fmt.Sprintf("%s/link[%d]", path, i+1), SpecCAPFeeds, "link to the CAP alert %q should be absolute", link.Href)
+		}
+	}
+	_, _, err := entry.EmbeddedAlert()
+	switch {
+	case err == ErrNoEmbeddedAlert:
+		if capLinks == 0 {
+			v.add(path, SpecCAPFeeds, "entry has no link of type %s to its CAP alert", CAPMediaType)
+		}
+	case err != nil:
+		v.add(path+"/content", SpecCAPFeeds, "embedded CAP alert cannot be decoded: %v", err)
+	}
+	if isEmptyText(&entry.Summary) {
+		v.add(path, SpecCAPFeeds, "entry should have a summary")
+	}
+}
+
+// checkContent checks the content and the summary it requires
+func (v *violations) checkContent(path string, entry *Entry) {
+	content := &entry.Content
+	contentPath := path + "/content"
+	if isEmptyText(content) && content.Type == "" {
+		if !hasAlternate(entry.Link) {
+			v.add(path, SpecRFC4287, "entry has neither content nor an alternate link")
+		}
+		return
+	}
+	v.checkIRI(contentPath, content.Base)
+	switch content.Type {
+	case "", "text", "html", "xhtml":
+		if content.Src != "" {
+			v.add(contentPath, SpecRFC4287, "content with a src attribute must have a media type, not %q", firstNonEmpty(content.Type, "text"))
+		}
+	default:
+		if !strings.Contains(content.Type, "/") {
+			v.add(contentPath, SpecRFC4287, "invalid content type %q", content.Type)
+		} else if strings.HasPrefix(strings.ToLower(content.Type), "multipart/") {
+			v.add(contentPath, SpecRFC4287, "content type %q must not be composite", content.Type)
+		}
+	}
+	if content.Src != "" {
+		v.checkIRI(contentPath, content.Src)
+		if strings.TrimSpace(content.Content) != "" || strings.TrimSpace(content.Body) != "" {
+			v.add(contentPath, SpecRFC4287, "content with a src attribute must be empty")
+		}
+		if isEmptyText(&entry.Summary) {
+			v.add(path, SpecRFC4287, "entry with out-of-line content must have a summary")
+		}
+	} else if base64Content(content.Type) && isEmptyText(&entry.Summary) {
+		v.add(path, SpecRFC4287, "entry with base64 encoded content must have a summary")
+	}
+}
+
+func (v *violations) checkID(path string, id string) {
+	if strings.TrimSpace(id) == "" {
+		v.add(path+"/id", SpecRFC4287, "missing id")
+		return
+	}
+	if u, err := url.Parse(id); err != nil || !u.IsAbs() {
+		v.add(path+"/id", SpecRFC4287, "id %q is not an absolute IRI", id)
+	}
+}
+
+func (v *violations) checkText(path string, text *Text, required bool) {
+	switch text.Type {
+	case "", "text", "html", "xhtml":
+	default:
+		v.add(path, SpecRFC4287, "invalid text type %q, expected text, html or xhtml", text.Type)
+	}
+	if required && isEmptyText(text) {
+		v.add(path, SpecRFC4287, "missing %s", path[strings.LastIndex(path, "/")+1:])
+	}
+	v.checkIRI(path, text.Base)
+}
+
+func (v *violations) checkDate(path string, date TimeStr, required bool) {
+	if date == "" {
+		if required {
+			v.add(path, SpecRFC4287, "missing %s", path[strings.LastIndex(path, "/")+1:])
+		}
+		return
+	}
+	if _, err := TimeParse(TimeStr(strings.TrimSpace(string(date)))); err != nil {
+		v.add(path, SpecRFC4287, "date %q is not an RFC 3339 date-time", date)
+	}
+}
+
+func (v *violations) checkPersons(path string, persons []Person) {
+	for i, person := range persons {
+		personPath := fmt.Sprintf("%s[%d]", path, i+1)
+		if strings.TrimSpace(person.Name) == "" {
+			v.add(personPath, SpecRFC4287, "missing name")
+		}
+		v.checkIRI(personPath, person.URI)
+	}
+}
+
+func (v *violations) checkLinks(path string, links []Link) {
+	for i, link := range links {
+		linkPath := fmt.Sprintf("%s/link[%d]", path, i+1)
+		if link.Href == "" {
+			v.add(linkPath, SpecRFC4287, "missing href")
+		} else {
+			v.checkIRI(linkPath, link.Href)
+		}
+		v.checkIRI(linkPath, link.Base)
+	}
+}
+
+// checkCategories checks that every category has a term, skipping cap:category
+// elements, which decode into Category with their value as content
+func (v *violations) checkCategories(path string, categories []Category) {
+	for i, category := range categories {
+		if _, ok := cap.VersionOf(category.Space); ok {
+			continue
+		}
+		if strings.TrimSpace(category.Term) == "" {
+			v.add(fmt.Sprintf("%s/category[%d]", path, i+1), SpecRFC4287, "missing term")
+		}
+	}
+}
+
+// checkIRI checks that iri is a valid IRI reference, relative references are
+// checked as they are, without resolving them against xml:base
+func (v *violations) checkIRI(path string, iri string) {
+	if iri == "" {
+		return
+	}
+	if _, err := url.Parse(strings.TrimSpace(iri)); err != nil || strings.ContainsAny(iri, " <>\"{}|\\^`") {
+		v.add(path, SpecRFC4287, "%q is not a valid IRI", iri)
+	}
+}
+
+func isEmptyText(text *Text) bool {
+	return strings.TrimSpace(text.Content) == "" && strings.TrimSpace(text.Body) == "" && text.Src == ""
+}
+
+func hasAlternate(links []Link) bool {
+	for i := range links {
+		if links[i].Relation() == RelAlternate {
+			return true
+		}
+	}
+	return false
+}
+
+func sourceHasAuthor(entry *Entry) bool {
+	for _, source := range entry.Source {
+		if len(source.Author) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// base64Content - whether content of the media type is base64 encoded, which
+// is the case for media types other than XML and text ones
+func base64Content(mediaType string) bool {
+	switch mediaType {
+	case "", "text", "html", "xhtml":
+		return false
+	}
+	mediaType = strings.ToLower(mediaType)
+	return !strings.HasPrefix(mediaType, "text/") && !strings.HasSuffix(mediaType, "+xml") && !strings.HasSuffix(mediaType, "/xml")
+}
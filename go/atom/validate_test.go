@@ -0,0 +1,111 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"testing"
+
+	"github.com/IBM/cap/go/cap"
+	"github.com/stretchr/testify/assert"
+)
+
+func violationsOf(t *testing.T, feed *Feed) ValidationError {
+	err := feed.Validate()
+	if err == nil {
+		return nil
+	}
+	violations, ok := err.(ValidationError)
+	if !ok {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	return violations
+}
+
+func TestValidatePublishedFeed(t *testing.T) {
+	feed, err := testPublisher().Feed([]*cap.Alert{getAmberAlertExample(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, feed.Validate())
+}
+
+func TestValidateNWSFeed(t *testing.T) {
+	feed, err := getNwsAtomFeedExample()
+	if err != nil {
+		t.Fatal(err)
+	}
+	violations := violationsOf(t, feed)
+	// the NWS meets RFC 4287 but does not give its links the CAP media type
+	assert.Equal(t, len(feed.Entries), len(violations))
+	for _, violation := range violations {
+		assert.Equal(t, SpecCAPFeeds, violation.Spec)
+		assert.Equal(t, "entry has no link of type application/cap+xml to its CAP alert", violation.Message)
+	}
+	assert.Equal(t, "feed/entry[1]", violations[0].Path)
+}
+
+func TestValidateReportsAllViolations(t *testing.T) {
+	feed := &Feed{
+		ID:      "not an iri",
+		Updated: "yesterday",
+		Link:    []Link{{Href: "https://alerts.example.com/"}, {Rel: "self"}},
+		Entries: []Entry{
+			{
+				ID:      "urn:entry:1",
+				Title:   Text{Content: "one", Type: "markdown"},
+				Updated: "2018-08-15T14:52:00-08:00",
+				Link:    []Link{{Href: "alerts/1.xml", Type: CAPMediaType}},
+				Content: Text{Src: "https://alerts.example.com/alerts/1.xml", Type: CAPMediaType},
+			},
+			{
+				ID:       "urn:entry:1",
+				Title:    Text{Content: "duplicate"},
+				Updated:  "2018-08-15T14:52:00-08:00",
+				Author:   []Person{{Name: "sender"}},
+				Summary:  Text{Content: "summary"},
+				Category: []Category{{Content: "Met", Space: cap.Namespace11}, {Content: "Geo"}, {Label: "Weather"}},
+				Link:     []Link{{Href: "https://alerts.example.com/alerts/2.xml", Type: CAPMediaType}},
+				// src may be a relative IRI reference
+				Content: Text{Src: "alerts/2.xml"},
+			},
+		},
+	}
+	var messages []string
+	for _, violation := range violationsOf(t, feed) {
+		messages = append(messages, violation.String())
+	}
+	assert.Equal(t, []string{
+		`feed/id: id "not an iri" is not an absolute IRI (RFC 4287)`,
+		`feed/title: missing title (RFC 4287)`,
+		`feed/updated: date "yesterday" is not an RFC 3339 date-time (RFC 4287)`,
+		`feed/link[2]: missing href (RFC 4287)`,
+		`feed/entry[1]: entry has no author and the feed has no author (RFC 4287)`,
+		`feed/entry[1]/title: invalid text type "markdown", expected text, html or xhtml (RFC 4287)`,
+		`feed/entry[1]: entry with out-of-line content must have a summary (RFC 4287)`,
+		`feed/entry[1]/link[1]: link to the CAP alert "alerts/1.xml" should be absolute (CAP-feeds)`,
+		`feed/entry[1]: entry should have a summary (CAP-feeds)`,
+		`feed/entry[2]/id: id "urn:entry:1" is also the id of entry[1] (RFC 4287)`,
+		`feed/entry[2]/category[2]: missing term (RFC 4287)`,
+		`feed/entry[2]/category[3]: missing term (RFC 4287)`,
+		`feed/entry[2]/content: content with a src attribute must have a media type, not "text" (RFC 4287)`,
+	}, messages)
+}
+
+func TestValidationErrorMessage(t *testing.T) {
+	err := (&Feed{ID: "urn:feed", Title: Text{Content: "feed"}, Updated: "2018-08-15T14:52:00-08:00", Link: []Link{{}}}).Validate()
+	assert.Equal(t, "1 violations: feed/link[1]: missing href (RFC 4287)", err.Error())
+}
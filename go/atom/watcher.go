@@ -0,0 +1,188 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/IBM/cap/go/cap"
+)
+
+// DefaultWatchInterval matches the two minute update interval of the NWS feeds
+const DefaultWatchInterval time.Duration = 2 * time.Minute
+
+// EventType - the kind of change reported by a Watcher
+type EventType int
+
+// Event types
+const (
+	EventAdded   EventType = iota // EventAdded - the entry's ID was not in the previous feed
+	EventUpdated                  // EventUpdated - the entry's Updated time changed
+	EventRemoved                  // EventRemoved - the entry's ID is no longer in the feed
+	EventError                    // EventError - the feed could not be retrieved
+)
+
+// Event - a change between successive polls of a feed
+type Event struct {
+	Type EventType
+	// Entry - the entry, for EventRemoved the last version seen
+	Entry Entry
+	// Alert - the entry's alert, for EventAdded and EventUpdated when the
+	// Watcher fetches alerts and the fetch succeeded
+	Alert *cap.VersionedAlert
+	// Err - for EventError the feed error, otherwise the alert fetch error
+	Err error
+}
+
+// Watcher - polls a feed and reports the entries added, updated and removed
+type Watcher struct {
+	Client      *Client       // Client - used to retrieve the feed, DefaultClient when nil
+	URL         string        // URL - the feed, the NWS national feed when empty
+	Interval    time.Duration // Interval - between polls, DefaultWatchInterval when zero
+	Jitter      time.Duration // Jitter - a random delay of up to Jitter is added to each Interval
+	FetchAlerts bool          // FetchAlerts - whether to retrieve the alert of added and updated entries
+}
+
+// Watch starts polling the feed, the first poll reports every entry as added.
+// The returned channel is closed once ctx is done.
+func (w *Watcher) Watch(ctx context.Context) <-chan Event {
+	events := make(chan Event)
+	go w.run(ctx, events)
+	return events
+}
+
+func (w *Watcher) run(ctx context.Context, events chan<- Event) {
+	defer close(events)
+	known := make(map[string]Entry)
+	// the watcher keeps its own validators so that watchers sharing a Client
+	// do not see each other's not modified responses
+	var v validator
+	for {
+		if !w.poll(ctx, known, &v, events) {
+			return
+		}
+		timer := time.NewTimer(w.delay())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// poll retrieves the feed and sends the events for the changes since the
+// previous poll, it returns false once ctx is done
+func (w *Watcher) poll(ctx context.Context, known map[string]Entry, v *validator, events chan<- Event) bool {
+	feedURL := w.client().defaultFeed(w.URL)
+	next := *v
+	body, _, err := w.client().get(ctx, feedURL, feedAccept, &next)
+	var feed *Feed
+	if err == nil {
+		feed, _, err = w.client().decodeFeed(feedURL, body)
+	}
+	if err == ErrNotModified {
+		return ctx.Err() == nil
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return false
+		}
+		return send(ctx, events, Event{Type: EventError, Err: err})
+	}
+	*v = next
+
+	current := make(map[string]bool, len(feed.Entries))
+	for _, entry := range feed.Entries {
+		current[entry.ID] = true
+		previous, ok := known[entry.ID]
+		if ok && previous.Updated == entry.Updated {
+			continue
+		}
+		event := Event{Type: EventAdded, Entry: entry}
+		if ok {
+			event.Type = EventUpdated
+		}
+		if w.FetchAlerts {
+			event.Alert, _, event.Err = w.client().GetEntryAlert(ctx, &event.Entry)
+		}
+		if !send(ctx, events, event) {
+			return false
+		}
+		known[entry.ID] = entry
+	}
+
+	var removed []string
+	for id := range known {
+		if !current[id] {
+			removed = append(removed, id)
+		}
+	}
+	sort.Strings(removed)
+	for _, id := range removed {
+		if !send(ctx, events, Event{Type: EventRemoved, Entry: known[id]}) {
+			return false
+		}
+		delete(known, id)
+	}
+	return true
+}
+
+func (w *Watcher) client() *Client {
+	if w.Client == nil {
+		return DefaultClient
+	}
+	return w.Client
+}
+
+func (w *Watcher) delay() time.Duration {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	if w.Jitter > 0 {
+		interval += time.Duration(rand.Int63n(int64(w.Jitter) + 1))
+	}
+	return interval
+}
+
+func send(ctx context.Context, events chan<- Event, event Event) bool {
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// String - the name of the event type
+func (t EventType) String() string {
+	switch t {
+	case EventAdded:
+		return "added"
+	case EventUpdated:
+		return "updated"
+	case EventRemoved:
+		return "removed"
+	case EventError:
+		return "error"
+	}
+	return "unknown"
+}
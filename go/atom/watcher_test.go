@@ -0,0 +1,152 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package atom
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// feedServer serves the feeds in order, repeating the last one with a 304
+// when the client sends its ETag
+type feedServer struct {
+	mu    sync.Mutex
+	feeds []Feed
+	polls int
+}
+
+func (s *feedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	index := s.polls
+	if index >= len(s.feeds) {
+		index = len(s.feeds) - 1
+	}
+	etag := string(rune('a' + index))
+	s.polls++
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if s.feeds[index].ID == "" {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	feed := testFeed{ID: s.feeds[index].ID}
+	for _, entry := range s.feeds[index].Entries {
+		feed.Entries = append(feed.Entries, testFeedEntry{ID: entry.ID, Updated: entry.Updated})
+	}
+	xmlData, _ := xml.Marshal(feed)
+	w.Write(xmlData)
+}
+
+// testFeed - the minimal feed written by feedServer
+type testFeed struct {
+	XMLName xml.Name        `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string          `xml:"id"`
+	Entries []testFeedEntry `xml:"entry"`
+}
+
+type testFeedEntry struct {
+	ID      string  `xml:"id"`
+	Updated TimeStr `xml:"updated"`
+}
+
+func receive(t *testing.T, events <-chan Event, n int) []Event {
+	var received []Event
+	for len(received) < n {
+		select {
+		case event := <-events:
+			received = append(received, event)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out after %d of %d events", len(received), n)
+		}
+	}
+	return received
+}
+
+func TestWatcherEmitsAddedUpdatedAndRemoved(t *testing.T) {
+	server := httptest.NewServer(&feedServer{feeds: []Feed{
+		{ID: "feed", Entries: []Entry{{ID: "a", Updated: "1"}, {ID: "b", Updated: "1"}}},
+		{ID: "feed", Entries: []Entry{{ID: "a", Updated: "2"}, {ID: "c", Updated: "1"}}},
+		{}, // an error
+		{ID: "feed", Entries: []Entry{{ID: "a", Updated: "2"}, {ID: "c", Updated: "1"}}},
+	}})
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// a client without retries, so that the 503 is reported
+	watcher := Watcher{Client: &Client{}, URL: server.URL, Interval: time.Millisecond, Jitter: time.Millisecond}
+	events := watcher.Watch(ctx)
+	received := receive(t, events, 6)
+
+	assert.Equal(t, EventAdded, received[0].Type)
+	assert.Equal(t, "a", received[0].Entry.ID)
+	assert.Equal(t, EventAdded, received[1].Type)
+	assert.Equal(t, "b", received[1].Entry.ID)
+	assert.Equal(t, EventUpdated, received[2].Type)
+	assert.Equal(t, "a", received[2].Entry.ID)
+	assert.Equal(t, TimeStr("2"), received[2].Entry.Updated)
+	assert.Equal(t, EventAdded, received[3].Type)
+	assert.Equal(t, "c", received[3].Entry.ID)
+	assert.Equal(t, EventRemoved, received[4].Type)
+	assert.Equal(t, "b", received[4].Entry.ID)
+	assert.Equal(t, EventError, received[5].Type)
+	assert.Equal(t, "HTTP status code: 503", received[5].Err.Error())
+
+	// the unchanged feed produces no further events, and cancel closes the channel
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected event %v %s", event.Type, event.Entry.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+	cancel()
+	for range events {
+	}
+}
+
+func TestWatcherFetchesAlerts(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+	feed := httptest.NewServer(&feedServer{feeds: []Feed{
+		{ID: "feed", Entries: []Entry{{ID: server.URL + "/alert", Updated: "1"}}},
+	}})
+	defer feed.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watcher := Watcher{URL: feed.URL, Interval: time.Hour, FetchAlerts: true}
+	received := receive(t, watcher.Watch(ctx), 1)
+	// the test feed has no links
+	assert.Nil(t, received[0].Alert)
+	assert.Error(t, received[0].Err)
+}
+
+func TestEventTypeString(t *testing.T) {
+	assert.Equal(t, "added", EventAdded.String())
+	assert.Equal(t, "removed", EventRemoved.String())
+	assert.Equal(t, "unknown", EventType(99).String())
+}
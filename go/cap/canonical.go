@@ -0,0 +1,284 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// canonicalTimeFormat - CAP requires UTC to be written as -00:00 rather than Z
+const canonicalTimeFormat = "2006-01-02T15:04:05-00:00"
+
+// Canonical returns a normalized copy of the alert, for comparing alerts
+// received through different channels. Whitespace is collapsed, times are
+// converted to UTC, coordinates are reformatted, unordered collections
+// (addresses, codes, categories, parameters, geocodes, references, infos,
+// areas, ...) are sorted, characters XML does not allow are replaced and
+// unrecognized attributes and elements are dropped. The canonical alert is
+// not intended for display.
+func (a *Alert) Canonical() *Alert {
+	c := Alert{
+		Identifier:  normalizeText(a.Identifier),
+		Sender:      normalizeText(a.Sender),
+		Sent:        normalizeTime(a.Sent),
+		Status:      normalizeText(a.Status),
+		MsgType:     normalizeText(a.MsgType),
+		Source:      normalizeText(a.Source),
+		Scope:       normalizeText(a.Scope),
+		Restriction: normalizeText(a.Restriction),
+		Addresses:   normalizeAddresses(a.Addresses),
+		Code:        normalizeSet(a.Code),
+		Note:        normalizeText(a.Note),
+		References:  normalizeReferences(a.References),
+		Incidents:   normalizeSet(a.Incidents),
+	}
+	for i := range a.Info {
+		c.Info = append(c.Info, a.Info[i].canonical())
+	}
+	sortByXML(len(c.Info), func(i int) interface{} { return c.Info[i] }, func(i, j int) {
+		c.Info[i], c.Info[j] = c.Info[j], c.Info[i]
+	})
+	return &c
+}
+
+// Hash returns a stable hex encoded SHA-256 hash of the canonical form of the
+// alert, alerts which differ only in formatting have the same hash
+func (a *Alert) Hash() string {
+	data, err := xml.Marshal(a.Canonical())
+	if err != nil {
+		// the strings of the canonical form only hold characters XML allows,
+		// so marshaling cannot fail
+		panic(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (info *Info) canonical() Info {
+	c := Info{
+		Language:     strings.ToLower(normalizeText(info.Language)),
+		Category:     normalizeSet(info.Category),
+		Event:        normalizeText(info.Event),
+		ResponseType: normalizeSet(info.ResponseType),
+		Urgency:      normalizeText(info.Urgency),
+		Severity:     normalizeText(info.Severity),
+		Certainty:    normalizeText(info.Certainty),
+		Audience:     normalizeText(info.Audience),
+		EventCode:    normalizeNamedValues(info.EventCode, false),
+		Effective:    normalizeTime(info.Effective),
+		Onset:        normalizeTime(info.Onset),
+		Expires:      normalizeTime(info.Expires),
+		SenderName:   normalizeText(info.SenderName),
+		Headline:     normalizeText(info.Headline),
+		Description:  normalizeText(info.Description),
+		Instruction:  normalizeText(info.Instruction),
+		Web:          normalizeText(info.Web),
+		Contact:      normalizeText(info.Contact),
+		Parameter:    normalizeNamedValues(info.Parameter, false),
+	}
+	if c.Language == "" {
+		// language defaults to en-US when not present
+		c.Language = "en-us"
+	}
+	for _, resource := range info.Resource {
+		c.Resource = append(c.Resource, Resource{
+			ResourceDesc: normalizeText(resource.ResourceDesc),
+			MIMEType:     strings.ToLower(normalizeText(resource.MIMEType)),
+			Size:         resource.Size,
+			URI:          normalizeText(resource.URI),
+			DerefURI:     strings.Join(strings.Fields(xmlText(resource.DerefURI)), ""),
+			Digest:       strings.ToLower(normalizeText(resource.Digest)),
+		})
+	}
+	sortByXML(len(c.Resource), func(i int) interface{} { return c.Resource[i] }, func(i, j int) {
+		c.Resource[i], c.Resource[j] = c.Resource[j], c.Resource[i]
+	})
+	for _, area := range info.Area {
+		c.Area = append(c.Area, Area{
+			AreaDesc: normalizeText(area.AreaDesc),
+			Polygon:  normalizeCoordinates(area.Polygon),
+			Circle:   normalizeCoordinates(area.Circle),
+			Geocode:  normalizeNamedValues(area.Geocode, true),
+			Altitude: normalizeNumber(area.Altitude),
+			Ceiling:  normalizeNumber(area.Ceiling),
+		})
+	}
+	sortByXML(len(c.Area), func(i int) interface{} { return c.Area[i] }, func(i, j int) {
+		c.Area[i], c.Area[j] = c.Area[j], c.Area[i]
+	})
+	return c
+}
+
+func normalizeText(s string) string {
+	return strings.Join(strings.Fields(xmlText(s)), " ")
+}
+
+// xmlText replaces invalid UTF-8 and the characters XML does not allow, such
+// as control characters, with U+FFFD
+func xmlText(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r',
+			r >= 0x20 && r <= 0xD7FF,
+			r >= 0xE000 && r <= 0xFFFD,
+			r >= 0x10000 && r <= 0x10FFFF:
+			return r
+		}
+		return utf8.RuneError
+	}, s)
+}
+
+// normalizeAddresses sorts and de-duplicates the space delimited addresses,
+// addresses holding spaces are enclosed in double quotes
+func normalizeAddresses(addresses string) string {
+	var set []string
+	for i, part := range strings.Split(addresses, `"`) {
+		if i%2 == 1 {
+			// between double quotes, a single address
+			if address := normalizeText(part); address != "" {
+				set = append(set, `"`+address+`"`)
+			}
+			continue
+		}
+		set = append(set, strings.Fields(part)...)
+	}
+	return strings.Join(normalizeSet(set), " ")
+}
+
+// normalizeTime converts a parsable time to UTC, others are only trimmed
+func normalizeTime(t TimeStr) TimeStr {
+	trimmed := TimeStr(strings.TrimSpace(xmlText(string(t))))
+	parsed, err := TimeParse(trimmed)
+	if err != nil {
+		return trimmed
+	}
+	return TimeStr(parsed.UTC().Format(canonicalTimeFormat))
+}
+
+// normalizeSet normalizes, de-duplicates and sorts the values, dropping empty ones
+func normalizeSet(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var set []string
+	for _, value := range values {
+		value = normalizeText(value)
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		set = append(set, value)
+	}
+	sort.Strings(set)
+	return set
+}
+
+// normalizeReferences normalizes the whitespace delimited sender,identifier,sent
+// triples, references may be split across several elements
+func normalizeReferences(references []string) []string {
+	var triples []string
+	for _, reference := range references {
+		for _, triple := range strings.Fields(reference) {
+			parts := strings.Split(triple, ",")
+			if len(parts) == 3 {
+				parts[2] = string(normalizeTime(TimeStr(parts[2])))
+			}
+			triples = append(triples, strings.Join(parts, ","))
+		}
+	}
+	triples = normalizeSet(triples)
+	if len(triples) == 0 {
+		return nil
+	}
+	return []string{strings.Join(triples, " ")}
+}
+
+// normalizeNamedValues normalizes and sorts the values, when split is set
+// values holding whitespace delimited lists (as geocodes may) are split up
+func normalizeNamedValues(values []NamedValue, split bool) []NamedValue {
+	var normalized []NamedValue
+	for _, value := range values {
+		name := normalizeText(value.ValueName)
+		if !split {
+			normalized = append(normalized, NamedValue{ValueName: name, Value: normalizeText(value.Value)})
+			continue
+		}
+		for _, v := range normalizeSet(strings.Fields(value.Value)) {
+			normalized = append(normalized, NamedValue{ValueName: name, Value: v})
+		}
+	}
+	sort.Slice(normalized, func(i, j int) bool {
+		if normalized[i].ValueName != normalized[j].ValueName {
+			return normalized[i].ValueName < normalized[j].ValueName
+		}
+		return normalized[i].Value < normalized[j].Value
+	})
+	return normalized
+}
+
+// normalizeCoordinates reformats the numbers of polygons and circles and sorts
+// them, empty polygons and circles are dropped
+func normalizeCoordinates(values []string) []string {
+	var normalized []string
+	for _, value := range values {
+		fields := strings.Fields(value)
+		for i, field := range fields {
+			numbers := strings.Split(field, ",")
+			for j, number := range numbers {
+				numbers[j] = normalizeNumber(number)
+			}
+			fields[i] = strings.Join(numbers, ",")
+		}
+		normalized = append(normalized, strings.Join(fields, " "))
+	}
+	return normalizeSet(normalized)
+}
+
+func normalizeNumber(s string) string {
+	s = strings.TrimSpace(xmlText(s))
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return s
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// sortByXML sorts a collection of structs by their marshaled XML
+func sortByXML(n int, get func(i int) interface{}, swap func(i, j int)) {
+	keys := make([]string, n)
+	for i := range keys {
+		data, _ := xml.Marshal(get(i))
+		keys[i] = string(data)
+	}
+	sort.Sort(byKey{keys: keys, swap: swap})
+}
+
+type byKey struct {
+	keys []string
+	swap func(i, j int)
+}
+
+func (b byKey) Len() int           { return len(b.keys) }
+func (b byKey) Less(i, j int) bool { return b.keys[i] < b.keys[j] }
+func (b byKey) Swap(i, j int) {
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+	b.swap(i, j)
+}
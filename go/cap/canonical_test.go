@@ -0,0 +1,141 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const relayedAlertXML = `<?xml version="1.0"?>
+<cap:alert xmlns:cap="urn:oasis:names:tc:emergency:cap:1.1" xmlns:relay="urn:example:relay">
+  <cap:identifier>  KAR0-0306112239-SW </cap:identifier>
+  <cap:sender>KARO@CLETS.DOJ.CA.GOV</cap:sender>
+  <cap:sent>2003-06-12T05:39:00-00:00</cap:sent>
+  <cap:status>Actual</cap:status>
+  <cap:msgType>Alert</cap:msgType>
+  <cap:source>SW</cap:source>
+  <cap:scope>Public</cap:scope>
+  <cap:info>
+    <cap:language>es-US</cap:language>
+    <cap:category>Rescue</cap:category>
+    <cap:event>Abducción de Niño</cap:event>
+    <cap:urgency>Immediate</cap:urgency>
+    <cap:severity>Severe</cap:severity>
+    <cap:certainty>Likely</cap:certainty>
+    <cap:eventCode><cap:valueName>SAME</cap:valueName><cap:value>CAE</cap:value></cap:eventCode>
+    <cap:senderName>Departamento de Policía de Los Ángeles - LAPD</cap:senderName>
+    <cap:headline>Alerta Amber en el condado de Los Ángeles</cap:headline>
+    <cap:description>DATE/TIME: 06/11/03, 1915 HORAS. VÍCTIMAS: KHAYRI DOE JR. M/B BLK/BRO 3'0", 40 LIBRAS. TEZ LIGERA. DOB 06/24/01. CORTOCIRCUITOS ROJOS QUE USAN, CAMISETA BLANCA, COLLAR DE W/BLUE. LOCALIZACIÓN: 5721 DOE ST., LOS ÁNGELES. SOSPECHOSO: KHAYRI DOE ST. DOB 04/18/71 M/B, PELO DEL NEGRO, OJO DE BRO. VEHÍCULO: 81' BUICK 2-DR, AZUL (4XXX000)</cap:description>
+    <cap:contact>DET. SMITH, 77TH DIV, LOS ANGELES POLICE DEPT-LAPD AT 213 485-2389</cap:contact>
+    <cap:area>
+      <cap:areaDesc>condado de Los Ángeles</cap:areaDesc>
+      <cap:geocode><cap:valueName>SAME</cap:valueName><cap:value>006037</cap:value></cap:geocode>
+    </cap:area>
+  </cap:info>
+  <cap:info>
+    <cap:language>en-US</cap:language>
+    <cap:category>Rescue</cap:category>
+    <cap:event>Child Abduction</cap:event>
+    <cap:urgency>Immediate</cap:urgency>
+    <cap:severity>Severe</cap:severity>
+    <cap:certainty>Likely</cap:certainty>
+    <cap:eventCode><cap:valueName>SAME</cap:valueName><cap:value>CAE</cap:value></cap:eventCode>
+    <cap:senderName>Los Angeles Police Dept - LAPD</cap:senderName>
+    <cap:headline>Amber Alert in Los Angeles County</cap:headline>
+    <cap:description>DATE/TIME: 06/11/03, 1915 HRS.  VICTIM(S): KHAYRI DOE JR. M/B BLK/BRO 3'0", 40 LBS. LIGHT COMPLEXION.
+      DOB 06/24/01. WEARING RED SHORTS, WHITE T-SHIRT, W/BLUE COLLAR.  LOCATION: 5721 DOE ST., LOS ANGELES, CA.
+      SUSPECT(S): KHAYRI DOE SR. DOB 04/18/71 M/B, BLK HAIR, BRO EYE. VEHICLE: 81' BUICK 2-DR, BLUE (4XXX000).</cap:description>
+    <cap:contact>DET. SMITH, 77TH DIV, LOS ANGELES POLICE DEPT-LAPD AT 213 485-2389</cap:contact>
+    <cap:area>
+      <cap:areaDesc>Los Angeles County</cap:areaDesc>
+      <cap:geocode><cap:valueName>SAME</cap:valueName><cap:value>006037</cap:value></cap:geocode>
+    </cap:area>
+  </cap:info>
+  <relay:received>2003-06-12T05:40:00-00:00</relay:received>
+</cap:alert>`
+
+func TestHashIgnoresFormattingDifferences(t *testing.T) {
+	alert, err := getCAPAlertExample()
+	if err != nil {
+		t.Fatal(err)
+	}
+	relayed, err := ParseAlert11([]byte(relayedAlertXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, alert.Canonical(), relayed.Canonical())
+	assert.Equal(t, alert.Hash(), relayed.Hash())
+	assert.Equal(t, 64, len(alert.Hash()))
+}
+
+func TestHashChangesWithContent(t *testing.T) {
+	alert, err := getCAPAlertExample()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := alert.Hash()
+	alert.Info[0].Urgency = "Expected"
+	assert.NotEqual(t, hash, alert.Hash())
+}
+
+func TestCanonicalNormalizesValues(t *testing.T) {
+	alert := Alert{
+		Sent:       "2018-08-15T14:52:00-08:00",
+		Code:       []string{"IPAWSv1.0", " IPAWSv1.0", "", "CMAS"},
+		References: []string{"b@x,2,2018-08-15T14:52:00-08:00  a@x,1,2018-08-15T22:00:00+00:00", "a@x,1,2018-08-15T22:00:00+00:00"},
+		Info: []Info{{
+			Category: []string{"Safety", "Met"},
+			Parameter: []NamedValue{
+				{ValueName: "VTEC", Value: "/O.NEW/"},
+				{ValueName: "EAS-ORG", Value: "WXR"},
+			},
+			Area: []Area{{
+				Polygon: []string{"31.620,-86.35  31.59,-86.5\n31.96,-86.8 31.620,-86.35", ""},
+				Geocode: []NamedValue{{ValueName: "UGC", Value: "TXC201 TXC039"}},
+				Ceiling: "1000.0",
+			}},
+		}},
+	}
+	c := alert.Canonical()
+	assert.Equal(t, TimeStr("2018-08-15T22:52:00-00:00"), c.Sent)
+	assert.Equal(t, []string{"CMAS", "IPAWSv1.0"}, c.Code)
+	assert.Equal(t, []string{"a@x,1,2018-08-15T22:00:00-00:00 b@x,2,2018-08-15T22:52:00-00:00"}, c.References)
+	assert.Equal(t, "en-us", c.Info[0].Language)
+	assert.Equal(t, []string{"Met", "Safety"}, c.Info[0].Category)
+	assert.Equal(t, "EAS-ORG", c.Info[0].Parameter[0].ValueName)
+	assert.Equal(t, []string{"31.62,-86.35 31.59,-86.5 31.96,-86.8 31.62,-86.35"}, c.Info[0].Area[0].Polygon)
+	assert.Equal(t, []NamedValue{{ValueName: "UGC", Value: "TXC039"}, {ValueName: "UGC", Value: "TXC201"}}, c.Info[0].Area[0].Geocode)
+	assert.Equal(t, "1000", c.Info[0].Area[0].Ceiling)
+	// the original is not modified
+	assert.Equal(t, "IPAWSv1.0", alert.Code[0])
+}
+
+func TestCanonicalSortsAddresses(t *testing.T) {
+	a := Alert{Addresses: `b@example.com "Los Angeles  EOC" a@example.com`}
+	b := Alert{Addresses: ` a@example.com b@example.com a@example.com "Los Angeles EOC"`}
+	assert.Equal(t, `"Los Angeles EOC" a@example.com b@example.com`, a.Canonical().Addresses)
+	assert.Equal(t, a.Hash(), b.Hash())
+}
+
+func TestHashAcceptsCharactersXMLDoesNotAllow(t *testing.T) {
+	alert := Alert{Identifier: "id\x00", Note: "bad \xff utf-8 \x1b[0m"}
+	assert.Equal(t, "id�", alert.Canonical().Identifier)
+	assert.Equal(t, "bad � utf-8 �[0m", alert.Canonical().Note)
+	assert.Equal(t, 64, len(alert.Hash()))
+}
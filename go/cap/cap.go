@@ -26,6 +26,13 @@ import (
 // TODO consider adding enums
 // TODO add json conversion
 
+// CAP XML namespaces for each version of the specification
+const (
+	Namespace10 string = "http://www.incident.com/cap/1.0"
+	Namespace11 string = "urn:oasis:names:tc:emergency:cap:1.1"
+	Namespace12 string = "urn:oasis:names:tc:emergency:cap:1.2"
+)
+
 // Alert - This struct is for a CAP Alert Message (version 1.2)
 type Alert struct {
 	XMLName xml.Name `xml:"urn:oasis:names:tc:emergency:cap:1.2 alert"`
@@ -44,6 +51,9 @@ type Alert struct {
 	References  []string `xml:"references,omitempty"`  // References - References the most recent message to which the current message refers or replaces.
 	Incidents   []string `xml:"incidents,omitempty"`   // Incidents - Note: in the xsd but not explained in CAP 1.2 documentation
 	Info        []Info   `xml:"info,omitempty"`        // Info - The container for all component parts of the info element.
+
+	Attrs     Attrs       `xml:",any,attr"`      // Attrs - unrecognized attributes, re-emitted when marshaling.
+	Extension []Extension `xml:",any,omitempty"` // Extension - unrecognized elements (e.g. signatures), re-emitted unchanged where they were read.
 }
 
 // Alert11 CAP v1.1 Alert Message
@@ -52,6 +62,12 @@ type Alert11 struct {
 	XMLName xml.Name `xml:"urn:oasis:names:tc:emergency:cap:1.1 alert"` // TODO ensure this is actually a duplicate
 }
 
+// Alert10 CAP v1.0 Alert Message, whose named values are "valueName=value" text
+type Alert10 struct {
+	Alert
+	XMLName xml.Name `xml:"http://www.incident.com/cap/1.0 alert"`
+}
+
 // Info -
 type Info struct {
 	XMLName xml.Name `xml:"info"`
@@ -77,6 +93,8 @@ type Info struct {
 	Parameter    []NamedValue `xml:"parameter,omitempty"`    // Parameter - Denotes additional information associated with the alert message.
 	Resource     []Resource   `xml:"resource,omitempty"`     // Resource - in the xsd but not explained in CAP 1.2 documentation.
 	Area         []Area       `xml:"area,omitempty"`         // Area - array of area elements associated with the alert message.
+	Attrs        Attrs        `xml:",any,attr"`              // Attrs - unrecognized attributes, re-emitted when marshaling.
+	Extension    []Extension  `xml:",any,omitempty"`         // Extension - unrecognized elements, re-emitted unchanged where they were read.
 }
 
 // Resource - Note: in the xsd but not explained in CAP 1.2 documentation
@@ -89,6 +107,9 @@ type Resource struct {
 	URI          string `xml:"uri,omitempty"`
 	DerefURI     string `xml:"derefUri,omitempty"`
 	Digest       string `xml:"digest,omitempty"`
+
+	Attrs     Attrs       `xml:",any,attr"`      // Attrs - unrecognized attributes, re-emitted when marshaling.
+	Extension []Extension `xml:",any,omitempty"` // Extension - unrecognized elements, re-emitted unchanged where they were read.
 }
 
 // Area - The container for all sub-elements of the area element.
@@ -101,11 +122,20 @@ type Area struct {
 	Geocode  []NamedValue `xml:"geocode,omitempty"`  // Geocode - The geographic code delineating the affected area of the alert message.
 	Altitude string       `xml:"altitude,omitempty"` // TODO need a xs:decimal type here // Note: in the xsd but not explained in CAP 1.2 documentation
 	Ceiling  string       `xml:"ceiling,omitempty"`  // TODO need a xs:decimal type here // Note: in the xsd but not explained in CAP 1.2 documentation
+
+	Attrs     Attrs       `xml:",any,attr"`      // Attrs - unrecognized attributes, re-emitted when marshaling.
+	Extension []Extension `xml:",any,omitempty"` // Extension - unrecognized elements, re-emitted unchanged where they were read.
 }
 
 // NamedValue -
 type NamedValue = shared.NamedValue
 
+// Extension - used for preserving unrecognized elements
+type Extension = shared.Extension
+
+// Attrs - used for preserving unrecognized attributes
+type Attrs = shared.Attrs
+
 // TimeStr - is a date/time in the CAPTimeFormat format
 type TimeStr = shared.TimeStr
 
@@ -143,6 +173,143 @@ func ParseAlert11(xmlData []byte) (*Alert11, error) {
 	return &alert, nil
 }
 
+// ParseAlert10 parses XML bytes into a CAP 1.0 Alert
+func ParseAlert10(xmlData []byte) (*Alert10, error) {
+	var alert Alert10
+
+	err := xml.Unmarshal(xmlData, &alert)
+	if err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+// UnmarshalXML decodes a CAP 1.2 alert
+func (a *Alert) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return decodeAlert(d, start, Namespace12, a)
+}
+
+// MarshalXML encodes the alert as a CAP 1.2 alert, its unrecognized elements
+// are written back where they were read
+func (a Alert) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return encodeAlert(e, start, Namespace12, &a)
+}
+
+// UnmarshalXML decodes a CAP 1.1 alert
+func (a *Alert11) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	if err := decodeAlert(d, start, Namespace11, &a.Alert); err != nil {
+		return err
+	}
+	a.XMLName = start.Name
+	a.Alert.XMLName = xml.Name{}
+	return nil
+}
+
+// MarshalXML encodes the alert as a CAP 1.1 alert
+func (a Alert11) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return encodeAlert(e, start, Namespace11, &a.Alert)
+}
+
+// checkAlert returns the error encoding/xml returns for an element which is
+// not an alert of namespace
+func checkAlert(start xml.StartElement, namespace string) error {
+	if start.Name.Local != "alert" {
+		return xml.UnmarshalError("expected element type <alert> but have <" + start.Name.Local + ">")
+	}
+	if start.Name.Space != namespace {
+		have := start.Name.Space
+		if have == "" {
+			have = "no name space"
+		}
+		return xml.UnmarshalError("expected element <alert> in name space " + namespace + " but have " + have)
+	}
+	return nil
+}
+
+// decodeAlert decodes the alert element start, of the CAP version whose
+// namespace is namespace, into a
+func decodeAlert(d *xml.Decoder, start xml.StartElement, namespace string, a *Alert) error {
+	if err := checkAlert(start, namespace); err != nil {
+		return err
+	}
+	type Fields Alert
+	decoded := struct {
+		*Fields
+		XMLName xml.Name // XMLName - of any version, shadows the CAP 1.2 name
+		Inner   string   `xml:",innerxml"`
+	}{Fields: (*Fields)(a)}
+	if err := shared.DecodeElement(d, &start, &decoded, &decoded.Inner, &a.Extension); err != nil {
+		return err
+	}
+	a.XMLName = start.Name
+	return nil
+}
+
+func encodeAlert(e *xml.Encoder, start xml.StartElement, namespace string, a *Alert) error {
+	type Fields Alert
+	encoded := Fields(*a)
+	encoded.Extension = nil
+	start.Name = xml.Name{Space: namespace, Local: "alert"}
+	return shared.EncodeElement(e, start, encoded, a.Extension)
+}
+
+// UnmarshalXML decodes the info, recording where its unrecognized elements were
+func (info *Info) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type Fields Info
+	decoded := struct {
+		*Fields
+		Inner string `xml:",innerxml"`
+	}{Fields: (*Fields)(info)}
+	return shared.DecodeElement(d, &start, &decoded, &decoded.Inner, &info.Extension)
+}
+
+// MarshalXML encodes the info with its unrecognized elements where they were read
+func (info Info) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type Fields Info
+	encoded := Fields(info)
+	encoded.Extension = nil
+	start.Name = xml.Name{Local: "info"}
+	return shared.EncodeElement(e, start, encoded, info.Extension)
+}
+
+// UnmarshalXML decodes the resource, recording where its unrecognized elements were
+func (r *Resource) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type Fields Resource
+	decoded := struct {
+		*Fields
+		Inner string `xml:",innerxml"`
+	}{Fields: (*Fields)(r)}
+	return shared.DecodeElement(d, &start, &decoded, &decoded.Inner, &r.Extension)
+}
+
+// MarshalXML encodes the resource with its unrecognized elements where they were read
+func (r Resource) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type Fields Resource
+	encoded := Fields(r)
+	encoded.Extension = nil
+	start.Name = xml.Name{Local: "resource"}
+	return shared.EncodeElement(e, start, encoded, r.Extension)
+}
+
+// UnmarshalXML decodes the area, recording where its unrecognized elements were
+func (a *Area) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type Fields Area
+	decoded := struct {
+		*Fields
+		Inner string `xml:",innerxml"`
+	}{Fields: (*Fields)(a)}
+	return shared.DecodeElement(d, &start, &decoded, &decoded.Inner, &a.Extension)
+}
+
+// MarshalXML encodes the area with its unrecognized elements where they were read
+func (a Area) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type Fields Area
+	encoded := Fields(a)
+	encoded.Extension = nil
+	start.Name = xml.Name{Local: "area"}
+	return shared.EncodeElement(e, start, encoded, a.Extension)
+}
+
 // GetParameter returns back the value for the first parameter with the specified name
 func (info *Info) GetParameter(name string) string {
 	return shared.Search(&info.Parameter, name)
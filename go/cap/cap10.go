@@ -0,0 +1,280 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cap
+
+import (
+	"encoding/xml"
+	"strings"
+
+	"github.com/IBM/cap/go/shared"
+)
+
+// CAP 1.0 writes the eventCode, parameter and geocode named values as
+// "valueName=value" text rather than valueName and value elements. Alert10 is
+// decoded and encoded through the types below, which differ from Alert, Info
+// and Area only in their named values.
+
+// namedValue10 - a CAP 1.0 named value
+type namedValue10 NamedValue
+
+// UnmarshalXML splits the text at the first "=", named values written as
+// valueName and value elements, as in later versions, are also accepted
+func (nv *namedValue10) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var decoded struct {
+		ValueName string `xml:"valueName"`
+		Value     string `xml:"value"`
+		Text      string `xml:",chardata"`
+	}
+	if err := d.DecodeElement(&decoded, &start); err != nil {
+		return err
+	}
+	if decoded.ValueName != "" {
+		*nv = namedValue10{ValueName: decoded.ValueName, Value: decoded.Value}
+		return nil
+	}
+	text := strings.TrimSpace(decoded.Text)
+	if i := strings.Index(text, "="); i >= 0 {
+		*nv = namedValue10{ValueName: strings.TrimSpace(text[:i]), Value: strings.TrimSpace(text[i+1:])}
+	} else {
+		*nv = namedValue10{Value: text}
+	}
+	return nil
+}
+
+// MarshalXML writes the named value as "valueName=value"
+func (nv namedValue10) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(nv.ValueName+"="+nv.Value, start)
+}
+
+func toNamedValues10(values []NamedValue) []namedValue10 {
+	var converted []namedValue10
+	for _, value := range values {
+		converted = append(converted, namedValue10(value))
+	}
+	return converted
+}
+
+func fromNamedValues10(values []namedValue10) []NamedValue {
+	var converted []NamedValue
+	for _, value := range values {
+		converted = append(converted, NamedValue(value))
+	}
+	return converted
+}
+
+// info10 - Info with CAP 1.0 named values
+type info10 struct {
+	XMLName xml.Name `xml:"info"`
+
+	Language     string         `xml:"language,omitempty"`
+	Category     []string       `xml:"category"`
+	Event        string         `xml:"event"`
+	ResponseType []string       `xml:"responseType,omitempty"`
+	Urgency      string         `xml:"urgency"`
+	Severity     string         `xml:"severity"`
+	Certainty    string         `xml:"certainty"`
+	Audience     string         `xml:"audience,omitempty"`
+	EventCode    []namedValue10 `xml:"eventCode,omitempty"`
+	Effective    TimeStr        `xml:"effective,omitempty"`
+	Onset        TimeStr        `xml:"onset,omitempty"`
+	Expires      TimeStr        `xml:"expires,omitempty"`
+	SenderName   string         `xml:"senderName,omitempty"`
+	Headline     string         `xml:"headline,omitempty"`
+	Description  string         `xml:"description,omitempty"`
+	Instruction  string         `xml:"instruction,omitempty"`
+	Web          string         `xml:"web,omitempty"`
+	Contact      string         `xml:"contact,omitempty"`
+	Parameter    []namedValue10 `xml:"parameter,omitempty"`
+	Resource     []Resource     `xml:"resource,omitempty"`
+	Area         []area10       `xml:"area,omitempty"`
+	Attrs        Attrs          `xml:",any,attr"`
+	Extension    []Extension    `xml:",any,omitempty"`
+}
+
+// area10 - Area with CAP 1.0 named values
+type area10 struct {
+	XMLName xml.Name `xml:"area"`
+
+	AreaDesc  string         `xml:"areaDesc"`
+	Polygon   []string       `xml:"polygon,omitempty"`
+	Circle    []string       `xml:"circle,omitempty"`
+	Geocode   []namedValue10 `xml:"geocode,omitempty"`
+	Altitude  string         `xml:"altitude,omitempty"`
+	Ceiling   string         `xml:"ceiling,omitempty"`
+	Attrs     Attrs          `xml:",any,attr"`
+	Extension []Extension    `xml:",any,omitempty"`
+}
+
+// UnmarshalXML decodes the info, recording where its unrecognized elements were
+func (info *info10) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type Fields info10
+	decoded := struct {
+		*Fields
+		Inner string `xml:",innerxml"`
+	}{Fields: (*Fields)(info)}
+	return shared.DecodeElement(d, &start, &decoded, &decoded.Inner, &info.Extension)
+}
+
+// MarshalXML encodes the info with its unrecognized elements where they were read
+func (info info10) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type Fields info10
+	encoded := Fields(info)
+	encoded.Extension = nil
+	start.Name = xml.Name{Local: "info"}
+	return shared.EncodeElement(e, start, encoded, info.Extension)
+}
+
+// UnmarshalXML decodes the area, recording where its unrecognized elements were
+func (a *area10) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type Fields area10
+	decoded := struct {
+		*Fields
+		Inner string `xml:",innerxml"`
+	}{Fields: (*Fields)(a)}
+	return shared.DecodeElement(d, &start, &decoded, &decoded.Inner, &a.Extension)
+}
+
+// MarshalXML encodes the area with its unrecognized elements where they were read
+func (a area10) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type Fields area10
+	encoded := Fields(a)
+	encoded.Extension = nil
+	start.Name = xml.Name{Local: "area"}
+	return shared.EncodeElement(e, start, encoded, a.Extension)
+}
+
+func toInfo10(info *Info) info10 {
+	converted := info10{
+		Language:     info.Language,
+		Category:     info.Category,
+		Event:        info.Event,
+		ResponseType: info.ResponseType,
+		Urgency:      info.Urgency,
+		Severity:     info.Severity,
+		Certainty:    info.Certainty,
+		Audience:     info.Audience,
+		EventCode:    toNamedValues10(info.EventCode),
+		Effective:    info.Effective,
+		Onset:        info.Onset,
+		Expires:      info.Expires,
+		SenderName:   info.SenderName,
+		Headline:     info.Headline,
+		Description:  info.Description,
+		Instruction:  info.Instruction,
+		Web:          info.Web,
+		Contact:      info.Contact,
+		Parameter:    toNamedValues10(info.Parameter),
+		Resource:     info.Resource,
+		Attrs:        info.Attrs,
+		Extension:    info.Extension,
+	}
+	for _, area := range info.Area {
+		converted.Area = append(converted.Area, area10{
+			AreaDesc:  area.AreaDesc,
+			Polygon:   area.Polygon,
+			Circle:    area.Circle,
+			Geocode:   toNamedValues10(area.Geocode),
+			Altitude:  area.Altitude,
+			Ceiling:   area.Ceiling,
+			Attrs:     area.Attrs,
+			Extension: area.Extension,
+		})
+	}
+	return converted
+}
+
+func fromInfo10(info *info10) Info {
+	converted := Info{
+		Language:     info.Language,
+		Category:     info.Category,
+		Event:        info.Event,
+		ResponseType: info.ResponseType,
+		Urgency:      info.Urgency,
+		Severity:     info.Severity,
+		Certainty:    info.Certainty,
+		Audience:     info.Audience,
+		EventCode:    fromNamedValues10(info.EventCode),
+		Effective:    info.Effective,
+		Onset:        info.Onset,
+		Expires:      info.Expires,
+		SenderName:   info.SenderName,
+		Headline:     info.Headline,
+		Description:  info.Description,
+		Instruction:  info.Instruction,
+		Web:          info.Web,
+		Contact:      info.Contact,
+		Parameter:    fromNamedValues10(info.Parameter),
+		Resource:     info.Resource,
+		Attrs:        info.Attrs,
+		Extension:    info.Extension,
+	}
+	for _, area := range info.Area {
+		converted.Area = append(converted.Area, Area{
+			AreaDesc:  area.AreaDesc,
+			Polygon:   area.Polygon,
+			Circle:    area.Circle,
+			Geocode:   fromNamedValues10(area.Geocode),
+			Altitude:  area.Altitude,
+			Ceiling:   area.Ceiling,
+			Attrs:     area.Attrs,
+			Extension: area.Extension,
+		})
+	}
+	return converted
+}
+
+// UnmarshalXML decodes a CAP 1.0 alert
+func (a *Alert10) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	if err := checkAlert(start, Namespace10); err != nil {
+		return err
+	}
+	// the info elements are decoded into the shallower Info
+	type Fields Alert
+	decoded := struct {
+		*Fields
+		XMLName xml.Name
+		Info    []info10 `xml:"info"`
+		Inner   string   `xml:",innerxml"`
+	}{Fields: (*Fields)(&a.Alert)}
+	if err := shared.DecodeElement(d, &start, &decoded, &decoded.Inner, &a.Extension); err != nil {
+		return err
+	}
+	a.XMLName = start.Name
+	for i := range decoded.Info {
+		a.Info = append(a.Info, fromInfo10(&decoded.Info[i]))
+	}
+	return nil
+}
+
+// MarshalXML encodes the alert as a CAP 1.0 alert
+func (a Alert10) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type Fields Alert
+	fields := Fields(a.Alert)
+	fields.Info = nil
+	fields.Extension = nil
+	// the shallower Info is encoded after the fields of the alert, as the
+	// alert's would be
+	encoded := struct {
+		*Fields
+		Info []info10 `xml:"info,omitempty"`
+	}{Fields: &fields}
+	for i := range a.Info {
+		encoded.Info = append(encoded.Info, toInfo10(&a.Info[i]))
+	}
+	start.Name = xml.Name{Space: Namespace10, Local: "alert"}
+	return shared.EncodeElement(e, start, encoded, a.Extension)
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package cap
 
 import (
+	"encoding/xml"
 	"io/ioutil"
 	"testing"
 
@@ -161,3 +162,124 @@ func TestParseAlert11ReturnsErrForInvalidXml(t *testing.T) {
 	_, err := ParseAlert11([]byte("invalid xml"))
 	assert.Equal(t, "EOF", err.Error())
 }
+
+const extendedAlertXML = `<alert xmlns="urn:oasis:names:tc:emergency:cap:1.2" xmlns:v="urn:example:vendor" v:priority="high">
+	<identifier>EXT-1</identifier>
+	<sender>test@example.com</sender>
+	<sent>2018-08-15T14:52:00-08:00</sent>
+	<status>Actual</status>
+	<msgType>Alert</msgType>
+	<scope>Public</scope>
+	<info>
+		<event>Test</event>
+		<resource v:checked="true">
+			<resourceDesc>map</resourceDesc>
+			<mimeType>image/png</mimeType>
+			<v:thumbnail>map-small.png</v:thumbnail>
+		</resource>
+		<area>
+			<areaDesc>Somewhere</areaDesc>
+			<v:zone v:kind="fire">FZ-12</v:zone>
+		</area>
+		<v:profile>first</v:profile>
+		<v:profile>second</v:profile>
+	</info>
+	<Signature xmlns="http://www.w3.org/2000/09/xmldsig#"><SignedInfo><v:ref>abc</v:ref></SignedInfo></Signature>
+</alert>`
+
+func checkExtendedAlert(t *testing.T, alert *Alert) {
+	assert.Equal(t, 1, len(alert.Attrs))
+	assert.Equal(t, xml.Attr{Name: xml.Name{Space: "urn:example:vendor", Local: "priority"}, Value: "high"}, alert.Attrs[0])
+	assert.Equal(t, 1, len(alert.Extension))
+	assert.Equal(t, xml.Name{Space: "http://www.w3.org/2000/09/xmldsig#", Local: "Signature"}, alert.Extension[0].XMLName)
+	assert.Contains(t, alert.Extension[0].XML, `<ref xmlns="urn:example:vendor">abc</ref>`)
+
+	info := alert.Info[0]
+	assert.Equal(t, 2, len(info.Extension))
+	assert.Equal(t, "profile", info.Extension[0].XMLName.Local)
+	assert.Equal(t, "first", info.Extension[0].XML)
+	assert.Equal(t, "second", info.Extension[1].XML)
+
+	resource := info.Resource[0]
+	assert.Equal(t, "true", resource.Attrs[0].Value)
+	assert.Equal(t, "thumbnail", resource.Extension[0].XMLName.Local)
+
+	area := info.Area[0]
+	assert.Equal(t, "Somewhere", area.AreaDesc)
+	assert.Equal(t, xml.Name{Space: "urn:example:vendor", Local: "zone"}, area.Extension[0].XMLName)
+	assert.Equal(t, "fire", area.Extension[0].Attrs[0].Value)
+	assert.Equal(t, "FZ-12", area.Extension[0].XML)
+}
+
+func TestUnmarshalAlertPreservesUnknownElementsAndAttributes(t *testing.T) {
+	alert, err := ParseAlert([]byte(extendedAlertXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkExtendedAlert(t, alert)
+}
+
+func TestMarshalAlertReemitsUnknownElementsAndAttributes(t *testing.T) {
+	alert, err := ParseAlert([]byte(extendedAlertXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	xmlData, err := xml.Marshal(alert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	relayed, err := ParseAlert(xmlData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkExtendedAlert(t, relayed)
+	assert.Equal(t, alert, relayed)
+}
+
+const signature = `<ds:Signature xmlns:ds="http://www.w3.org/2000/09/xmldsig#">` +
+	`<ds:SignedInfo>` +
+	`<ds:CanonicalizationMethod Algorithm="http://www.w3.org/TR/2001/REC-xml-c14n-20010315"/>` +
+	`<ds:SignatureMethod Algorithm="http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"/>` +
+	`<ds:Reference URI=""><ds:Transforms><ds:Transform Algorithm="http://www.w3.org/2000/09/xmldsig#enveloped-signature"/></ds:Transforms>` +
+	`<ds:DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"/><ds:DigestValue>K9Kh3mCqN8TrZ4ZV3nGJfO8tGQ0c0jvKpnSsPGvWbLs=</ds:DigestValue></ds:Reference>` +
+	`</ds:SignedInfo>` +
+	`<ds:SignatureValue>dGhlIHNpZ25hdHVyZSB2YWx1ZQ==</ds:SignatureValue>` +
+	`</ds:Signature>`
+
+const signedAlertXML = `<alert xmlns="urn:oasis:names:tc:emergency:cap:1.2" xmlns:v="urn:example:vendor">` +
+	`<identifier>SIGNED-1</identifier>` +
+	`<sender>test@example.com</sender>` +
+	`<v:origin system="relay">EAS</v:origin>` +
+	`<sent>2018-08-15T14:52:00-08:00</sent>` +
+	`<status>Actual</status>` +
+	`<msgType>Alert</msgType>` +
+	`<scope>Public</scope>` +
+	`<info><category>Met</category><event>Test</event><urgency>Immediate</urgency><severity>Minor</severity><certainty>Observed</certainty></info>` +
+	signature +
+	`</alert>`
+
+func TestMarshalAlertKeepsSignedExtensionsUnchanged(t *testing.T) {
+	alert, err := ParseAlert([]byte(signedAlertXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	xmlData, err := xml.Marshal(alert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the signature and its prefixes are written back byte for byte, the
+	// vendor element where it was, declaring the prefix it inherited
+	assert.Contains(t, string(xmlData), `</info>`+signature+`</alert>`)
+	assert.Contains(t, string(xmlData), `</sender><v:origin xmlns:v="urn:example:vendor" system="relay">EAS</v:origin><sent>`)
+
+	relayed, err := ParseAlert(xmlData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, alert, relayed)
+	relayedData, err := xml.Marshal(relayed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(xmlData), string(relayedData))
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cap
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Deduplicator - remembers the keys of the most recently seen alerts, up to a
+// fixed window size, so duplicates can be filtered out of an ingestion loop.
+// The least recently seen key is forgotten when the window is full. A
+// Deduplicator is safe for concurrent use.
+type Deduplicator struct {
+	mu     sync.Mutex
+	size   int
+	recent *list.List // recent - keys, most recently seen first
+	seen   map[string]*list.Element
+}
+
+// NewDeduplicator creates a Deduplicator remembering up to size keys
+func NewDeduplicator(size int) *Deduplicator {
+	if size < 1 {
+		size = 1
+	}
+	return &Deduplicator{
+		size:   size,
+		recent: list.New(),
+		seen:   make(map[string]*list.Element, size),
+	}
+}
+
+// Duplicate reports whether an alert with the same canonical Hash is in the
+// window, and records the alert as seen
+func (d *Deduplicator) Duplicate(alert *Alert) bool {
+	return d.DuplicateKey(alert.Hash())
+}
+
+// DuplicateKey reports whether the key is in the window, and records it as seen
+func (d *Deduplicator) DuplicateKey(key string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if element, ok := d.seen[key]; ok {
+		d.recent.MoveToFront(element)
+		return true
+	}
+	d.seen[key] = d.recent.PushFront(key)
+	if d.recent.Len() > d.size {
+		oldest := d.recent.Back()
+		d.recent.Remove(oldest)
+		delete(d.seen, oldest.Value.(string))
+	}
+	return false
+}
+
+// Len returns the number of keys in the window
+func (d *Deduplicator) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.recent.Len()
+}
+
+// Filter forwards the alerts received on in which are not duplicates, the
+// returned channel is closed once in is closed
+func (d *Deduplicator) Filter(in <-chan *Alert) <-chan *Alert {
+	out := make(chan *Alert)
+	go func() {
+		defer close(out)
+		for alert := range in {
+			if !d.Duplicate(alert) {
+				out <- alert
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeduplicatorForgetsLeastRecentlySeen(t *testing.T) {
+	d := NewDeduplicator(2)
+	assert.False(t, d.DuplicateKey("a"))
+	assert.False(t, d.DuplicateKey("b"))
+	assert.True(t, d.DuplicateKey("a"))
+	assert.False(t, d.DuplicateKey("c")) // evicts b
+	assert.Equal(t, 2, d.Len())
+	assert.True(t, d.DuplicateKey("a"))
+	assert.False(t, d.DuplicateKey("b"))
+}
+
+func TestDeduplicatorFilterDropsDuplicateAlerts(t *testing.T) {
+	alert, err := getCAPAlertExample()
+	if err != nil {
+		t.Fatal(err)
+	}
+	relayed, err := ParseAlert11([]byte(relayedAlertXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	update := *alert
+	update.MsgType = "Update"
+
+	in := make(chan *Alert)
+	go func() {
+		in <- alert
+		in <- &relayed.Alert
+		in <- &update
+		in <- alert
+		close(in)
+	}()
+	var received []*Alert
+	for a := range NewDeduplicator(10).Filter(in) {
+		received = append(received, a)
+	}
+	assert.Equal(t, []*Alert{alert, &update}, received)
+}
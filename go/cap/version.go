@@ -0,0 +1,184 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+)
+
+// Version - a version of the CAP specification
+type Version string
+
+// Supported versions of the CAP specification
+const (
+	Version10 Version = "1.0"
+	Version11 Version = "1.1"
+	Version12 Version = "1.2"
+)
+
+// Namespace returns the XML namespace of the version or ""
+func (v Version) Namespace() string {
+	switch v {
+	case Version10:
+		return Namespace10
+	case Version11:
+		return Namespace11
+	case Version12:
+		return Namespace12
+	}
+	return ""
+}
+
+// VersionOf returns the version whose XML namespace is namespace
+func VersionOf(namespace string) (Version, bool) {
+	switch namespace {
+	case Namespace10:
+		return Version10, true
+	case Namespace11:
+		return Version11, true
+	case Namespace12:
+		return Version12, true
+	}
+	return "", false
+}
+
+// NotCAPError - returned for documents which are not CAP alerts, such as the
+// HTML error page of a server
+type NotCAPError struct {
+	Root        xml.Name // Root - the root element of the document, empty if it is not XML
+	ContentType string   // ContentType - the media type the document was served as, if known
+	Err         error    // Err - the XML syntax error, if the document is not XML
+}
+
+func (e *NotCAPError) Error() string {
+	document := "document"
+	if e.ContentType != "" {
+		document = fmt.Sprintf("document of type %s", e.ContentType)
+	}
+	switch {
+	case e.Err != nil:
+		return fmt.Sprintf("%s is not a CAP alert: %v", document, e.Err)
+	case e.Root.Local == "alert":
+		return fmt.Sprintf("%s is not a CAP alert: unsupported namespace %q", document, e.Root.Space)
+	default:
+		return fmt.Sprintf("%s is not a CAP alert: root element is <%s>", document, e.Root.Local)
+	}
+}
+
+// DetectVersion returns the CAP version of the alert in xmlData from the
+// namespace of its root element, a *NotCAPError is returned if xmlData is not
+// a CAP alert
+func DetectVersion(xmlData []byte) (Version, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(xmlData))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", &NotCAPError{Err: err}
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local == "alert" {
+			if version, ok := VersionOf(start.Name.Space); ok {
+				return version, nil
+			}
+		}
+		return "", &NotCAPError{Root: start.Name}
+	}
+}
+
+// VersionedAlert - an alert of any CAP version. The embedded Alert holds its
+// content whatever the version, Version records the version it was parsed from
+// and is marshaled as, CAP 1.2 when empty.
+type VersionedAlert struct {
+	Alert
+	Version Version `xml:"-"`
+}
+
+// UnmarshalXML decodes an alert of any CAP version, recording its version
+func (v *VersionedAlert) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	version, ok := VersionOf(start.Name.Space)
+	if !ok || start.Name.Local != "alert" {
+		return &NotCAPError{Root: start.Name}
+	}
+	v.Version = version
+	switch version {
+	case Version10:
+		var alert Alert10
+		if err := d.DecodeElement(&alert, &start); err != nil {
+			return err
+		}
+		v.Alert = alert.Alert
+	case Version11:
+		var alert Alert11
+		if err := d.DecodeElement(&alert, &start); err != nil {
+			return err
+		}
+		v.Alert = alert.Alert
+	default:
+		v.Alert = Alert{}
+		return d.DecodeElement(&v.Alert, &start)
+	}
+	return nil
+}
+
+// MarshalXML encodes the alert as an alert of its version, in the namespace
+// of the version
+func (v VersionedAlert) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	switch v.Version {
+	case Version10:
+		return e.EncodeElement(v.Alert10(), start)
+	case Version11:
+		return e.EncodeElement(v.Alert11(), start)
+	case Version12, "":
+		return e.EncodeElement(v.Alert, start)
+	}
+	return fmt.Errorf("unsupported CAP version %q", v.Version)
+}
+
+// Alert10 returns the alert as a CAP 1.0 Alert
+func (v *VersionedAlert) Alert10() *Alert10 {
+	return &Alert10{Alert: v.Alert}
+}
+
+// Alert11 returns the alert as a CAP 1.1 Alert
+func (v *VersionedAlert) Alert11() *Alert11 {
+	return &Alert11{Alert: v.Alert}
+}
+
+// Alert12 returns the alert as a CAP 1.2 Alert
+func (v *VersionedAlert) Alert12() *Alert {
+	alert := v.Alert
+	return &alert
+}
+
+// ParseVersionedAlert parses XML bytes into an Alert of the CAP version given
+// by the namespace of the document, a *NotCAPError is returned if xmlData is
+// not a CAP alert
+func ParseVersionedAlert(xmlData []byte) (*VersionedAlert, error) {
+	if _, err := DetectVersion(xmlData); err != nil {
+		return nil, err
+	}
+	var parsed VersionedAlert
+	if err := xml.Unmarshal(xmlData, &parsed); err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
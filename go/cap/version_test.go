@@ -0,0 +1,189 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getCAPAlertExampleAs(t *testing.T, version Version) []byte {
+	xmlData, err := ioutil.ReadFile("../../resources/cap_amber_alert_example.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Replace(xmlData, []byte(Namespace12), []byte(version.Namespace()), 1)
+}
+
+func getCAP10Example(t *testing.T) []byte {
+	xmlData, err := ioutil.ReadFile("../../resources/cap_1_0_hsas_example.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return xmlData
+}
+
+func TestParseVersionedAlertDetectsVersion(t *testing.T) {
+	alert, err := ParseVersionedAlert(getCAP10Example(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Version10, alert.Version)
+	assert.Equal(t, "43b080713727", alert.Identifier)
+	assert.Equal(t, "Homeland Security Advisory System Update", alert.Info[0].Event)
+
+	for _, version := range []Version{Version11, Version12} {
+		alert, err := ParseVersionedAlert(getCAPAlertExampleAs(t, version))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, version, alert.Version)
+		assert.Equal(t, "KAR0-0306112239-SW", alert.Identifier)
+		assert.Equal(t, "006037", alert.Info[0].Area[0].GetGeocode("SAME"))
+	}
+}
+
+func TestParseAlert10SplitsNamedValues(t *testing.T) {
+	alert, err := ParseAlert10(getCAP10Example(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "ORANGE", alert.Info[0].GetParameter("HSAS"))
+	assert.Equal(t, "U.S. nationwide and interests worldwide", alert.Info[0].Area[0].AreaDesc)
+	assert.Equal(t, "http://www.dhs.gov/dhspublic/getAdvisoryImage", alert.Info[0].Resource[0].URI)
+
+	alert, err = ParseAlert10([]byte(`<alert xmlns="http://www.incident.com/cap/1.0"><identifier>1</identifier><info>` +
+		`<eventCode>SAME=CAE</eventCode><parameter> EAS-ORG = CIV </parameter>` +
+		`<area><areaDesc>Los Angeles County</areaDesc><geocode>FIPS6=006037</geocode><geocode>ZIP</geocode></area>` +
+		`</info></alert>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := alert.Info[0]
+	assert.Equal(t, []NamedValue{{ValueName: "SAME", Value: "CAE"}}, info.EventCode)
+	assert.Equal(t, "CIV", info.GetParameter("EAS-ORG"))
+	assert.Equal(t, "006037", info.Area[0].GetGeocode("FIPS6"))
+	// text without "=" has no name
+	assert.Equal(t, NamedValue{Value: "ZIP"}, info.Area[0].Geocode[1])
+}
+
+func TestAlert10MarshalsNamedValuesAsText(t *testing.T) {
+	alert, err := ParseAlert10(getCAP10Example(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	alert.Info[0].Area[0].AddGeocode("FIPS6", "006037")
+	data, err := xml.Marshal(alert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := string(data)
+	assert.Contains(t, doc, `<alert xmlns="http://www.incident.com/cap/1.0">`)
+	assert.Contains(t, doc, "<parameter>HSAS=ORANGE</parameter>")
+	assert.Contains(t, doc, "<geocode>FIPS6=006037</geocode>")
+	assert.NotContains(t, doc, "<valueName>")
+	// the elements keep their order, the password extension its place
+	assert.True(t, strings.Index(doc, "<web>") < strings.Index(doc, "<parameter>"))
+	assert.True(t, strings.Index(doc, "<parameter>") < strings.Index(doc, "<resource>"))
+	assert.True(t, strings.Index(doc, "<sender>") < strings.Index(doc, "<password>"))
+	assert.True(t, strings.Index(doc, "<password>") < strings.Index(doc, "<sent>"))
+
+	parsed, err := ParseAlert10(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, alert.Info[0].Parameter, parsed.Info[0].Parameter)
+	assert.Equal(t, "006037", parsed.Info[0].Area[0].GetGeocode("FIPS6"))
+}
+
+func TestVersionedAlertMarshalsAsEachVersion(t *testing.T) {
+	alert, err := ParseVersionedAlert(getCAPAlertExampleAs(t, Version11))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for version, value := range map[Version]interface{}{
+		Version10: alert.Alert10(),
+		Version11: alert.Alert11(),
+		Version12: alert.Alert12(),
+	} {
+		data, err := xml.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		detected, err := DetectVersion(data)
+		assert.Nil(t, err)
+		assert.Equal(t, version, detected)
+	}
+}
+
+func TestVersionedAlertRoundTrips(t *testing.T) {
+	for _, xmlData := range [][]byte{
+		getCAP10Example(t),
+		getCAPAlertExampleAs(t, Version11),
+		getCAPAlertExampleAs(t, Version12),
+	} {
+		alert, err := ParseVersionedAlert(xmlData)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := xml.Marshal(alert)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.NotContains(t, string(data), "<Version>")
+		detected, err := DetectVersion(data)
+		assert.Nil(t, err)
+		assert.Equal(t, alert.Version, detected)
+
+		var parsed VersionedAlert
+		if err := xml.Unmarshal(data, &parsed); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, *alert, parsed)
+	}
+}
+
+func TestDetectVersionReturnsNotCAPError(t *testing.T) {
+	for document, message := range map[string]string{
+		`<!DOCTYPE html><html><body>Service Unavailable</body></html>`:         "document is not a CAP alert: root element is <html>",
+		`<alert xmlns="urn:example:alerts"><identifier>1</identifier></alert>`: `document is not a CAP alert: unsupported namespace "urn:example:alerts"`,
+		`Service Unavailable`: "document is not a CAP alert: EOF",
+	} {
+		_, err := ParseVersionedAlert([]byte(document))
+		notCAP, ok := err.(*NotCAPError)
+		if assert.True(t, ok, document) {
+			assert.Equal(t, message, notCAP.Error())
+		}
+	}
+
+	err := &NotCAPError{Root: xml.Name{Local: "feed"}, ContentType: "application/atom+xml"}
+	assert.Equal(t, "document of type application/atom+xml is not a CAP alert: root element is <feed>", err.Error())
+}
+
+func TestVersionOf(t *testing.T) {
+	version, ok := VersionOf(Namespace10)
+	assert.True(t, ok)
+	assert.Equal(t, Version10, version)
+	_, ok = VersionOf("")
+	assert.False(t, ok)
+	assert.Equal(t, "", Version("2.0").Namespace())
+}
@@ -17,15 +17,61 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 	"strings"
 
 	"github.com/IBM/cap/go/atom"
+	"github.com/IBM/cap/go/filter"
 	"github.com/urfave/cli"
 )
 
+// feedFlags select a regional feed instead of the national feed
+var feedFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "state, s",
+		Usage: "only alerts for the `STATE`, e.g. TX",
+	},
+	cli.StringFlag{
+		Name:  "zone, z",
+		Usage: "only alerts for the county or zone `UGC`, e.g. TXC201 or TXZ213",
+	},
+}
+
+// getFeed retrieves the feed selected by the feedFlags
+func getFeed(c *cli.Context) (*atom.Feed, []byte, error) {
+	feedURL := atom.NwsNationalAtomFeedURL
+	state, zone := c.String("state"), c.String("zone")
+	var err error
+	switch {
+	case state != "" && zone != "":
+		return nil, nil, fmt.Errorf("--state and --zone cannot be combined")
+	case state != "":
+		feedURL, err = atom.StateFeedURL(state)
+	case zone != "":
+		feedURL, err = atom.ZoneFeedURL(zone)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return atom.DefaultClient.GetFeed(context.Background(), feedURL)
+}
+
+// getFilter compiles the --where filter, it returns nil without a filter
+func getFilter(c *cli.Context) (*filter.Filter, error) {
+	expr := c.String("where")
+	if expr == "" {
+		return nil, nil
+	}
+	where, err := filter.Compile(expr)
+	if syntaxErr, ok := err.(*filter.SyntaxError); ok {
+		return nil, fmt.Errorf("--where: %v\n%s", err, syntaxErr.Pointer())
+	}
+	return where, err
+}
+
 func main() {
 	app := cli.NewApp()
 	app.Name = "captn"
@@ -37,8 +83,9 @@ func main() {
 			Aliases:     []string{"p"},
 			Usage:       "pull nws atom feed",
 			Description: "Get the national weather service atom feed and dumps it as output",
+			Flags:       feedFlags,
 			Action: func(c *cli.Context) error {
-				_, raw, err := atom.GetFeed()
+				_, raw, err := getFeed(c)
 				if err != nil {
 					return err
 				}
@@ -54,21 +101,45 @@ func main() {
 			Description: `loads all CAP alert(s) of TYPE and dumps them as output, default TYPE is any/all.
 
    Examples: captn alert fire
-             captn alert flood`,
+             captn alert flood
+             captn alert --state TX flood
+             captn alert --where 'severity in (Severe, Extreme) and geocode.UGC startswith TX'`,
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:  "where, w",
+					Usage: "only alerts whose feed entry matches the `FILTER`, e.g. 'urgency = Immediate and event ~ flood'",
+				},
+			}, feedFlags...),
 			Action: func(c *cli.Context) error {
 				alertType := strings.ToLower(c.Args().Get(0))
-				feed, _, err := atom.GetFeed()
+				where, err := getFilter(c)
+				if err != nil {
+					return err
+				}
+				feed, _, err := getFeed(c)
 				if err != nil {
 					return err
 				}
+				var entries []atom.Entry
 				for _, entry := range feed.Entries {
-					if strings.Contains(strings.ToLower(entry.Event), alertType) {
-						_, raw, err := entry.Link[0].GetAlert()
-						if err != nil {
-							return err
-						}
-						fmt.Printf("%s", raw)
+					if !strings.Contains(strings.ToLower(entry.Event), alertType) {
+						continue
 					}
+					if where == nil || where.MatchEntry(&entry) {
+						entries = append(entries, entry)
+					}
+				}
+				failed := 0
+				for _, result := range atom.FetchAlerts(context.Background(), entries) {
+					if result.Err != nil {
+						log.Printf("%s: %v", result.Entry.ID, result.Err)
+						failed++
+						continue
+					}
+					fmt.Printf("%s", result.Raw)
+				}
+				if failed > 0 {
+					return fmt.Errorf("%d of %d alerts could not be retrieved", failed, len(entries))
 				}
 				return nil
 			},
@@ -0,0 +1,272 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edxl
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/IBM/cap/go/cap"
+	"github.com/IBM/cap/go/shared"
+)
+
+// Namespace is the XML namespace of the OASIS EDXL Distribution Element v1.0
+const Namespace string = "urn:oasis:names:tc:emergency:EDXL:DE:1.0"
+
+// CAPMIMEType is the media type used for CAP alerts carried as nonXMLContent
+const CAPMIMEType string = "application/cap+xml"
+
+// DefaultConfidentiality is the combinedConfidentiality used for public alerts
+const DefaultConfidentiality string = "UNCLASSIFIED AND NOT SENSITIVE"
+
+// EDXLDistribution - root structure of an EDXL Distribution Element (version 1.0)
+type EDXLDistribution struct {
+	XMLName xml.Name `xml:"urn:oasis:names:tc:emergency:EDXL:DE:1.0 EDXLDistribution"`
+
+	DistributionID          string            `xml:"distributionID"`                  // DistributionID - unique identifier for this distribution message.
+	SenderID                string            `xml:"senderID"`                        // SenderID - unique identifier of the sender, in the form actor@domain.
+	DateTimeSent            TimeStr           `xml:"dateTimeSent"`                    // DateTimeSent - the date and time the distribution message was sent.
+	DistributionStatus      string            `xml:"distributionStatus"`              // DistributionStatus - the actionability of the message (Actual, Exercise, System, Test).
+	DistributionType        string            `xml:"distributionType"`                // DistributionType - the function of the message (Report, Update, Cancel, ...).
+	CombinedConfidentiality string            `xml:"combinedConfidentiality"`         // CombinedConfidentiality - confidentiality of the combined distribution message's content.
+	Language                string            `xml:"language,omitempty"`              // Language - the primary language used in the payload.
+	SenderRole              []ValueList       `xml:"senderRole,omitempty"`            // SenderRole - the functional role of the sender.
+	RecipientRole           []ValueList       `xml:"recipientRole,omitempty"`         // RecipientRole - the functional role of the recipient.
+	Keyword                 []ValueList       `xml:"keyword,omitempty"`               // Keyword - the topic related to the distribution message.
+	DistributionReference   []string          `xml:"distributionReference,omitempty"` // DistributionReference - references a previous distribution message.
+	ExplicitAddress         []ExplicitAddress `xml:"explicitAddress,omitempty"`       // ExplicitAddress - identifies recipients of the distribution message.
+	TargetArea              []TargetArea      `xml:"targetArea,omitempty"`            // TargetArea - the geographic area targeted by the distribution message.
+	ContentObject           []ContentObject   `xml:"contentObject,omitempty"`         // ContentObject - the container for the payloads of the distribution message.
+}
+
+// ValueList - a list of values along with the URN of the list they come from
+type ValueList struct {
+	ValueListURN string   `xml:"valueListUrn"`
+	Value        []string `xml:"value"`
+}
+
+// ExplicitAddress - a list of addresses along with the scheme they belong to
+type ExplicitAddress struct {
+	ExplicitAddressScheme string   `xml:"explicitAddressScheme"`
+	ExplicitAddressValue  []string `xml:"explicitAddressValue"`
+}
+
+// TargetArea - the container for all sub-elements of the targetArea element.
+type TargetArea struct {
+	Circle      []string `xml:"circle,omitempty"`      // Circle - a center point and radius in the CAP "lat,lon radius" format.
+	Polygon     []string `xml:"polygon,omitempty"`     // Polygon - the paired values of points in the CAP "lat,lon lat,lon ..." format.
+	Country     []string `xml:"country,omitempty"`     // Country - an ISO 3166-1 country code.
+	Subdivision []string `xml:"subdivision,omitempty"` // Subdivision - an ISO 3166-2 subdivision code.
+	LocCodeUN   []string `xml:"locCodeUN,omitempty"`   // LocCodeUN - a UN/LOCODE location code.
+}
+
+// ContentObject - the container for a single payload and its metadata
+type ContentObject struct {
+	ContentDescription  string         `xml:"contentDescription,omitempty"`  // ContentDescription - human readable description of the payload.
+	ContentKeyword      []ValueList    `xml:"contentKeyword,omitempty"`      // ContentKeyword - the topic related to the payload.
+	IncidentID          string         `xml:"incidentID,omitempty"`          // IncidentID - the incident the payload is related to.
+	IncidentDescription string         `xml:"incidentDescription,omitempty"` // IncidentDescription - human readable description of the incident.
+	OriginatorRole      []ValueList    `xml:"originatorRole,omitempty"`      // OriginatorRole - the functional role of the payload originator.
+	ConsumerRole        []ValueList    `xml:"consumerRole,omitempty"`        // ConsumerRole - the functional role of the payload consumer.
+	Confidentiality     string         `xml:"confidentiality,omitempty"`     // Confidentiality - confidentiality of the payload.
+	NonXMLContent       *NonXMLContent `xml:"nonXMLContent,omitempty"`       // NonXMLContent - a payload that is not XML, or is XML carried by value or reference.
+	XMLContent          *XMLContent    `xml:"xmlContent,omitempty"`          // XMLContent - a payload of well formed XML.
+}
+
+// NonXMLContent - a payload carried as base64 data or referenced by uri
+type NonXMLContent struct {
+	MIMEType    string `xml:"mimeType"`              // MIMEType - the media type of the payload.
+	Size        int64  `xml:"size,omitempty"`        // Size - the size of the payload in bytes.
+	Digest      string `xml:"digest,omitempty"`      // Digest - the SHA-1 hash of the payload.
+	URI         string `xml:"uri,omitempty"`         // URI - where the payload can be retrieved.
+	ContentData string `xml:"contentData,omitempty"` // ContentData - the base64 encoded payload.
+}
+
+// XMLContent - a payload carried as embedded XML
+type XMLContent struct {
+	KeyXMLContent      []EmbeddedXML `xml:"keyXMLContent,omitempty"`      // KeyXMLContent - excerpts of the embedded content, for routing.
+	EmbeddedXMLContent []EmbeddedXML `xml:"embeddedXMLContent,omitempty"` // EmbeddedXMLContent - the embedded XML payload(s).
+}
+
+// EmbeddedXML - raw XML carried inside an xmlContent element
+type EmbeddedXML struct {
+	XML string `xml:",innerxml"`
+}
+
+// TimeStr - is a date/time in the EDXL dateTime format
+type TimeStr = shared.TimeStr
+
+// Parse parses XML bytes into an EDXLDistribution
+func Parse(xmlData []byte) (*EDXLDistribution, error) {
+	var dist EDXLDistribution
+
+	err := xml.Unmarshal(xmlData, &dist)
+	if err != nil {
+		return nil, err
+	}
+	return &dist, nil
+}
+
+// Alerts returns the CAP alerts carried in the distribution's content objects,
+// whether embedded as xmlContent or base64 encoded as nonXMLContent
+func (d *EDXLDistribution) Alerts() ([]*cap.Alert, error) {
+	var alerts []*cap.Alert
+	for index, content := range d.ContentObject {
+		if content.XMLContent != nil {
+			for _, embedded := range content.XMLContent.EmbeddedXMLContent {
+				if !isCAP([]byte(embedded.XML)) {
+					continue
+				}
+				alert, err := parseAlert([]byte(embedded.XML))
+				if err != nil {
+					return nil, fmt.Errorf("contentObject %d: %v", index, err)
+				}
+				alerts = append(alerts, alert)
+			}
+		}
+		if content.NonXMLContent != nil && content.NonXMLContent.ContentData != "" {
+			data, err := content.NonXMLContent.Data()
+			if err != nil {
+				return nil, fmt.Errorf("contentObject %d: %v", index, err)
+			}
+			if !isCAPMIMEType(content.NonXMLContent.MIMEType) && !isCAP(data) {
+				continue
+			}
+			alert, err := parseAlert(data)
+			if err != nil {
+				return nil, fmt.Errorf("contentObject %d: %v", index, err)
+			}
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts, nil
+}
+
+// Data returns the decoded base64 contentData
+func (n *NonXMLContent) Data() ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(n.ContentData), ""))
+}
+
+// Wrap wraps an alert in a new EDXLDistribution, the distribution metadata
+// is derived from the alert's identifier, sender, sent, status, msgType, scope
+// and areas
+func Wrap(alert *cap.Alert) (*EDXLDistribution, error) {
+	body, err := xml.Marshal(alert)
+	if err != nil {
+		return nil, err
+	}
+
+	dist := EDXLDistribution{
+		DistributionID:          alert.Identifier,
+		SenderID:                alert.Sender,
+		DateTimeSent:            alert.Sent,
+		DistributionStatus:      alert.Status,
+		DistributionType:        distributionType(alert.MsgType),
+		CombinedConfidentiality: confidentiality(alert.Scope, alert.Restriction),
+	}
+	if alert.Scope == "Private" && alert.Addresses != "" {
+		dist.ExplicitAddress = []ExplicitAddress{{
+			ExplicitAddressScheme: "cap:addresses",
+			ExplicitAddressValue:  strings.Fields(alert.Addresses),
+		}}
+	}
+	if len(alert.References) > 0 {
+		dist.DistributionReference = alert.References
+	}
+	for _, info := range alert.Info {
+		if dist.Language == "" {
+			dist.Language = info.Language
+		}
+		for _, area := range info.Area {
+			if len(area.Polygon) == 0 && len(area.Circle) == 0 {
+				continue
+			}
+			dist.TargetArea = append(dist.TargetArea, TargetArea{
+				Polygon: area.Polygon,
+				Circle:  area.Circle,
+			})
+		}
+	}
+	dist.ContentObject = []ContentObject{{
+		ContentDescription: headline(alert),
+		XMLContent: &XMLContent{
+			EmbeddedXMLContent: []EmbeddedXML{{XML: string(body)}},
+		},
+	}}
+	return &dist, nil
+}
+
+// distributionType maps a CAP msgType onto an EDXL distributionType
+func distributionType(msgType string) string {
+	switch msgType {
+	case "Alert":
+		return "Report"
+	case "Update", "Cancel", "Ack", "Error":
+		return msgType
+	}
+	return "Report"
+}
+
+// confidentiality maps a CAP scope onto an EDXL combinedConfidentiality
+func confidentiality(scope string, restriction string) string {
+	switch scope {
+	case "Public", "":
+		return DefaultConfidentiality
+	case "Restricted":
+		if restriction != "" {
+			return restriction
+		}
+	}
+	return strings.ToUpper(scope)
+}
+
+func headline(alert *cap.Alert) string {
+	for _, info := range alert.Info {
+		if info.Headline != "" {
+			return info.Headline
+		}
+	}
+	return ""
+}
+
+func isCAPMIMEType(mimeType string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(mimeType)), CAPMIMEType)
+}
+
+// isCAP reports whether the root element of xmlData is a CAP alert
+func isCAP(xmlData []byte) bool {
+	return rootName(xmlData).Local == "alert"
+}
+
+func rootName(xmlData []byte) xml.Name {
+	var root struct {
+		XMLName xml.Name
+	}
+	xml.Unmarshal(xmlData, &root)
+	return root.XMLName
+}
+
+// parseAlert parses a CAP 1.0, 1.1 or 1.2 alert depending on its namespace
+func parseAlert(xmlData []byte) (*cap.Alert, error) {
+	alert, err := cap.ParseVersionedAlert(xmlData)
+	if err != nil {
+		return nil, err
+	}
+	return &alert.Alert, nil
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package edxl
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"io/ioutil"
+	"testing"
+
+	"github.com/IBM/cap/go/cap"
+	"github.com/stretchr/testify/assert"
+)
+
+func getEDXLExample() (*EDXLDistribution, error) {
+	xmlData, err := ioutil.ReadFile("../../resources/edxl_de_example.xml")
+	if err != nil {
+		return nil, err
+	}
+	return Parse(xmlData)
+}
+
+func getCAPAlertExample() (*cap.Alert, error) {
+	xmlData, err := ioutil.ReadFile("../../resources/cap_amber_alert_example.xml")
+	if err != nil {
+		return nil, err
+	}
+	return cap.ParseAlert(xmlData)
+}
+
+func TestUnmarshalEDXLHasProperValues(t *testing.T) {
+	dist, err := getEDXLExample()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "CA-DOJ-0306112239", dist.DistributionID)
+	assert.Equal(t, "KARO@CLETS.DOJ.CA.GOV", dist.SenderID)
+	assert.Equal(t, "2003-06-11T22:40:00-07:00", string(dist.DateTimeSent))
+	assert.Equal(t, "Actual", dist.DistributionStatus)
+	assert.Equal(t, "Report", dist.DistributionType)
+	assert.Equal(t, "Police", dist.SenderRole[0].Value[0])
+	assert.Equal(t, "US-CA", dist.TargetArea[0].Subdivision[0])
+	assert.Equal(t, 3, len(dist.ContentObject))
+	assert.Equal(t, "image/jpeg", dist.ContentObject[2].NonXMLContent.MIMEType)
+}
+
+func TestEDXLAlertsReturnsEmbeddedAndEncodedAlerts(t *testing.T) {
+	dist, err := getEDXLExample()
+	if err != nil {
+		t.Fatal(err)
+	}
+	alerts, err := dist.Alerts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(alerts))
+	assert.Equal(t, "KAR0-0306112239-SW", alerts[0].Identifier)
+	assert.Equal(t, "Amber Alert in Los Angeles County", alerts[0].Info[0].Headline)
+	assert.Equal(t, "NOAA-NWS-ALERTS-TX1258B7A4D0C8.FloodWarning", alerts[1].Identifier)
+	assert.Equal(t, "TXC201", alerts[1].Info[0].Area[0].GetGeocode("UGC"))
+}
+
+func TestEDXLAlertsReturnsErrForInvalidContentData(t *testing.T) {
+	dist := EDXLDistribution{ContentObject: []ContentObject{{
+		NonXMLContent: &NonXMLContent{MIMEType: CAPMIMEType, ContentData: "not base64!"},
+	}}}
+	_, err := dist.Alerts()
+	assert.Error(t, err)
+}
+
+func TestEDXLAlertsSkipsNonCAPContent(t *testing.T) {
+	data := base64.StdEncoding.EncodeToString([]byte("<report><text>not an alert</text></report>"))
+	dist := EDXLDistribution{ContentObject: []ContentObject{
+		{NonXMLContent: &NonXMLContent{MIMEType: "text/xml", ContentData: data}},
+		{XMLContent: &XMLContent{EmbeddedXMLContent: []EmbeddedXML{{XML: "<report/>"}}}},
+	}}
+	alerts, err := dist.Alerts()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(alerts))
+}
+
+func TestWrapSetsDistributionMetadata(t *testing.T) {
+	alert, err := getCAPAlertExample()
+	if err != nil {
+		t.Fatal(err)
+	}
+	alert.Info[0].Area[0].Polygon = []string{"34.0,-118.5 34.3,-118.5 34.3,-118.1 34.0,-118.5"}
+	dist, err := Wrap(alert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, alert.Identifier, dist.DistributionID)
+	assert.Equal(t, alert.Sender, dist.SenderID)
+	assert.Equal(t, alert.Sent, dist.DateTimeSent)
+	assert.Equal(t, "Actual", dist.DistributionStatus)
+	assert.Equal(t, "Report", dist.DistributionType)
+	assert.Equal(t, DefaultConfidentiality, dist.CombinedConfidentiality)
+	assert.Equal(t, "en-US", dist.Language)
+	assert.Equal(t, 1, len(dist.TargetArea))
+	assert.Equal(t, alert.Info[0].Area[0].Polygon, dist.TargetArea[0].Polygon)
+	assert.Equal(t, "Amber Alert in Los Angeles County", dist.ContentObject[0].ContentDescription)
+}
+
+func TestWrapRoundTripsAlert(t *testing.T) {
+	alert, err := getCAPAlertExample()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dist, err := Wrap(alert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xmlData, err := xml.Marshal(dist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := Parse(xmlData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alerts, err := parsed.Alerts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(alerts))
+	assert.Equal(t, alert.Identifier, alerts[0].Identifier)
+	assert.Equal(t, alert.Info[1].Event, alerts[0].Info[1].Event)
+}
+
+func TestWrapMapsScopeAndMsgType(t *testing.T) {
+	alert := cap.Alert{Scope: "Restricted", Restriction: "FOR OFFICIAL USE ONLY", MsgType: "Cancel"}
+	dist, err := Wrap(&alert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "FOR OFFICIAL USE ONLY", dist.CombinedConfidentiality)
+	assert.Equal(t, "Cancel", dist.DistributionType)
+
+	alert = cap.Alert{Scope: "Private", Addresses: "ops@example.com fire@example.com"}
+	dist, err = Wrap(&alert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "PRIVATE", dist.CombinedConfidentiality)
+	assert.Equal(t, []string{"ops@example.com", "fire@example.com"}, dist.ExplicitAddress[0].ExplicitAddressValue)
+}
+
+func TestParseReturnsErrForInvalidXml(t *testing.T) {
+	_, err := Parse([]byte("invalid xml"))
+	assert.Equal(t, "EOF", err.Error())
+}
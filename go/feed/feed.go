@@ -0,0 +1,242 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package feed
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/IBM/cap/go/atom"
+	"github.com/IBM/cap/go/rss"
+)
+
+// Format - the syntax of a feed
+type Format string
+
+// Supported feed formats
+const (
+	FormatAtom Format = "atom"
+	FormatRSS  Format = "rss"
+)
+
+// Feed - a CAP index in any supported format
+type Feed struct {
+	Format  Format
+	ID      string    // ID - the Atom feed id, or the RSS channel link
+	Title   string    // Title - the title of the feed
+	Link    string    // Link - the web page of the feed
+	Updated time.Time // Updated - when the feed last changed, zero when unknown
+	Entries []Entry
+
+	Atom *atom.Feed // Atom - the parsed feed, for Atom feeds
+	RSS  *rss.RSS   // RSS - the parsed feed, for RSS feeds
+}
+
+// Entry - an entry of a CAP index in any supported format
+type Entry struct {
+	ID        string    // ID - the Atom entry id, or the RSS item GUID or link
+	Title     string    // Title - the title of the entry
+	Summary   string    // Summary - the Atom summary or the RSS description
+	Link      string    // Link - the web page of the entry
+	AlertURL  string    // AlertURL - the URL of the entry's CAP alert, "" when the entry has none
+	Updated   time.Time // Updated - when the entry last changed, zero when unknown
+	Published time.Time // Published - when the entry was first published, zero when unknown
+	Category  []string  // Category - the categories of the entry
+
+	Atom *atom.Entry // Atom - the parsed entry, for Atom feeds
+	RSS  *rss.Item   // RSS - the parsed item, for RSS feeds
+}
+
+// Get retrieves and parses the Atom or RSS 2.0 feed at feedURL with client, or
+// atom.DefaultClient when nil, so the request is made with the client's
+// User-Agent, retries, circuit breaker and size limit. The links of Atom feeds
+// are resolved against the feed's URL.
+func Get(ctx context.Context, client *atom.Client, feedURL string) (*Feed, []byte, error) {
+	if client == nil {
+		client = atom.DefaultClient
+	}
+	data, documentURL, err := client.GetFeedDocument(ctx, feedURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	feed, err := parse(data, documentURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	return feed, data, nil
+}
+
+// Parse parses an Atom or RSS 2.0 feed, the format is detected from the root element
+func Parse(data []byte) (*Feed, error) {
+	return parse(data, "")
+}
+
+// parse parses a feed retrieved from documentURL, which is empty when unknown
+func parse(data []byte, documentURL string) (*Feed, error) {
+	format, err := DetectFormat(data)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case FormatAtom:
+		var feed atom.Feed
+		if err := xml.Unmarshal(data, &feed); err != nil {
+			return nil, err
+		}
+		if documentURL != "" {
+			feed.ResolveLinks(documentURL)
+		}
+		return FromAtom(&feed), nil
+	default:
+		feed, err := rss.Parse(data)
+		if err != nil {
+			return nil, err
+		}
+		return FromRSS(feed), nil
+	}
+}
+
+// DetectFormat returns the format of the feed from its root element
+func DetectFormat(data []byte) (Format, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return "", fmt.Errorf("no root element")
+		}
+		if err != nil {
+			return "", err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch {
+		case start.Name.Space == atom.Namespace && start.Name.Local == "feed":
+			return FormatAtom, nil
+		case start.Name.Local == "rss":
+			return FormatRSS, nil
+		}
+		return "", fmt.Errorf("unsupported feed root element %s %s", start.Name.Space, start.Name.Local)
+	}
+}
+
+// FromAtom converts an Atom feed
+func FromAtom(f *atom.Feed) *Feed {
+	feed := &Feed{
+		Format:  FormatAtom,
+		ID:      f.ID,
+		Title:   strings.TrimSpace(f.Title.Content),
+		Link:    atomAlternate(f.Link),
+		Updated: atomTime(f.Updated),
+		Atom:    f,
+	}
+	for i := range f.Entries {
+		e := &f.Entries[i]
+		entry := Entry{
+			ID:        e.ID,
+			Title:     strings.TrimSpace(e.Title.Content),
+			Summary:   strings.TrimSpace(e.Summary.Content),
+			Link:      atomAlternate(e.Link),
+			AlertURL:  atomAlertURL(e),
+			Updated:   atomTime(e.Updated),
+			Published: atomTime(e.Published),
+			Atom:      e,
+		}
+		for _, category := range e.Category {
+			entry.Category = append(entry.Category, category.Term)
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed
+}
+
+// FromRSS converts an RSS feed
+func FromRSS(f *rss.RSS) *Feed {
+	channel := &f.Channel
+	feed := &Feed{
+		Format:  FormatRSS,
+		ID:      channel.Link,
+		Title:   strings.TrimSpace(channel.Title),
+		Link:    channel.Link,
+		Updated: rssTime(channel.LastBuildDate),
+		RSS:     f,
+	}
+	if feed.Updated.IsZero() {
+		feed.Updated = rssTime(channel.PubDate)
+	}
+	for i := range channel.Items {
+		item := &channel.Items[i]
+		published := rssTime(item.PubDate)
+		entry := Entry{
+			ID:       item.ID(),
+			Title:    strings.TrimSpace(item.Title),
+			Summary:  strings.TrimSpace(item.Description),
+			Link:     strings.TrimSpace(item.Link),
+			AlertURL: item.AlertURL(),
+			// RSS has no update time, items are republished when they change
+			Updated:   published,
+			Published: published,
+			RSS:       item,
+		}
+		for _, category := range item.Category {
+			entry.Category = append(entry.Category, strings.TrimSpace(category.Value))
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed
+}
+
+// atomAlternate returns the first alternate link, links without a rel are alternates
+func atomAlternate(links []atom.Link) string {
+	for _, link := range links {
+		if link.Relation() == atom.RelAlternate {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+// atomAlertURL returns the URL of the entry's CAP alert or ""
+func atomAlertURL(e *atom.Entry) string {
+	alertURL, err := e.AlertURL()
+	if err != nil {
+		return ""
+	}
+	return alertURL
+}
+
+func atomTime(t atom.TimeStr) time.Time {
+	parsed, err := atom.TimeParse(t)
+	if err != nil {
+		return time.Time{}
+	}
+	return parsed
+}
+
+func rssTime(s string) time.Time {
+	parsed, err := rss.ParseTime(s)
+	if err != nil {
+		return time.Time{}
+	}
+	return parsed
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package feed
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/IBM/cap/go/atom"
+	"github.com/stretchr/testify/assert"
+)
+
+func parseExample(t *testing.T, name string) *Feed {
+	xmlData, err := ioutil.ReadFile("../../resources/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := Parse(xmlData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return feed
+}
+
+func TestParseAtom(t *testing.T) {
+	feed := parseExample(t, "nws_atom_feed_example.xml")
+	assert.Equal(t, FormatAtom, feed.Format)
+	assert.Equal(t, "https://alerts.weather.gov/cap/us.php?x=0", feed.ID)
+	assert.Equal(t, "https://alerts.weather.gov/cap/us.php?x=0", feed.Link)
+	assert.Equal(t, time.Date(2018, 8, 15, 22, 57, 0, 0, time.UTC), feed.Updated.UTC())
+	assert.Equal(t, 163, len(feed.Entries))
+	assert.NotNil(t, feed.Atom)
+
+	entry := feed.Entries[0]
+	assert.Equal(t, "High Wind Warning issued August 15 at 2:52PM AKDT until August 16 at 7:00AM AKDT by NWS", entry.Title)
+	// the NWS links to the CAP alert without a media type
+	assert.Equal(t, entry.Atom.Link[0].Href, entry.AlertURL)
+	assert.Equal(t, entry.ID, entry.AlertURL)
+	assert.Equal(t, "High Wind Warning", entry.Atom.Event)
+	assert.False(t, entry.Updated.IsZero())
+}
+
+func TestParseRSS(t *testing.T) {
+	feed := parseExample(t, "rss_cap_feed_example.xml")
+	assert.Equal(t, FormatRSS, feed.Format)
+	assert.Equal(t, "https://alerts.example.org/", feed.ID)
+	assert.Equal(t, "Example Meteorological Service CAP Alerts", feed.Title)
+	assert.Equal(t, time.Date(2018, 8, 15, 22, 57, 0, 0, time.UTC), feed.Updated.UTC())
+	assert.Equal(t, 3, len(feed.Entries))
+	assert.NotNil(t, feed.RSS)
+
+	entry := feed.Entries[0]
+	assert.Equal(t, "urn:oid:2.49.0.0.999.0.2018.8.15.22.42.0", entry.ID)
+	assert.Equal(t, "https://alerts.example.org/cap/2018-0815-0042.xml", entry.AlertURL)
+	assert.Equal(t, "https://alerts.example.org/warnings/2018-0815-0042.html", entry.Link)
+	assert.Equal(t, []string{"Met"}, entry.Category)
+	assert.Equal(t, time.Date(2018, 8, 15, 22, 42, 0, 0, time.UTC), entry.Published.UTC())
+	assert.Equal(t, entry.Published, entry.Updated)
+	assert.Equal(t, []string{"Met", "Env"}, feed.Entries[1].Category)
+}
+
+func TestDetectFormat(t *testing.T) {
+	format, err := DetectFormat([]byte(`<?xml version="1.0"?><!-- index --><rss version="2.0"/>`))
+	assert.Nil(t, err)
+	assert.Equal(t, FormatRSS, format)
+	_, err = DetectFormat([]byte(`<alert xmlns="urn:oasis:names:tc:emergency:cap:1.2"/>`))
+	assert.Equal(t, "unsupported feed root element urn:oasis:names:tc:emergency:cap:1.2 alert", err.Error())
+	_, err = DetectFormat([]byte(``))
+	assert.Error(t, err)
+}
+
+func TestGet(t *testing.T) {
+	rssData, err := ioutil.ReadFile("../../resources/rss_cap_feed_example.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "test-agent", r.Header.Get("User-Agent"))
+		assert.Contains(t, r.Header.Get("Accept"), "application/rss+xml")
+		switch r.URL.Path {
+		case "/rss":
+			w.Header().Set("Content-Type", "application/rss+xml")
+			w.Write(rssData)
+		case "/cap/atom":
+			w.Header().Set("Content-Type", "application/atom+xml")
+			w.Write([]byte(`<feed xmlns="http://www.w3.org/2005/Atom"><id>urn:test</id>` +
+				`<entry><id>urn:test:1</id><link href="1.xml" type="application/cap+xml"/></entry></feed>`))
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><body>Not found</body></html>"))
+		}
+	}))
+	defer server.Close()
+	client := &atom.Client{BaseURL: server.URL, UserAgent: "test-agent"}
+
+	feed, data, err := Get(context.Background(), client, "/rss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, rssData, data)
+	assert.Equal(t, FormatRSS, feed.Format)
+	assert.Equal(t, 3, len(feed.Entries))
+	assert.Equal(t, "https://alerts.example.org/cap/2018-0815-0042.xml", feed.Entries[0].AlertURL)
+
+	// the links of Atom feeds are resolved against the feed's URL
+	feed, _, err = Get(context.Background(), client, "/cap/atom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, FormatAtom, feed.Format)
+	assert.Equal(t, server.URL+"/cap/1.xml", feed.Entries[0].AlertURL)
+
+	_, _, err = Get(context.Background(), client, "/missing")
+	_, ok := err.(*atom.ContentTypeError)
+	assert.True(t, ok)
+	assert.True(t, strings.Contains(err.Error(), "text/html"))
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/IBM/cap/go/cap"
+)
+
+// record - what a filter is evaluated against, an alert and one of its infos
+type record struct {
+	alert *cap.Alert
+	info  *cap.Info // info - nil for alerts without info
+}
+
+// field - a field of an alert which can be compared
+type field struct {
+	values func(r *record) []string
+	order  *ordering // order - how values are ordered, nil when they cannot be
+}
+
+// ordering - the order of the values of a field, by rank of the values of an
+// enumeration or as times
+type ordering struct {
+	values []string // values - of the enumeration from the lowest rank, nil for times
+}
+
+var timeOrder = &ordering{}
+
+// key returns the sort key of value
+func (o *ordering) key(value string) (int64, error) {
+	if o.values == nil {
+		t, err := cap.TimeParse(cap.TimeStr(value))
+		if err != nil {
+			return 0, fmt.Errorf("invalid time %q, expected a time like 2018-08-15T14:52:00-08:00", value)
+		}
+		return t.UnixNano(), nil
+	}
+	for rank, v := range o.values {
+		if strings.EqualFold(v, value) {
+			return int64(rank), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid value %q, expected one of %s", value, strings.Join(o.values, ", "))
+}
+
+func rankOf(values ...string) *ordering {
+	return &ordering{values: values}
+}
+
+// alertField returns a field of the alert
+func alertField(get func(a *cap.Alert) []string) *field {
+	return &field{values: func(r *record) []string { return get(r.alert) }}
+}
+
+// infoField returns a field of the info, which alerts without info do not have
+func infoField(get func(info *cap.Info) []string) *field {
+	return &field{values: func(r *record) []string {
+		if r.info == nil {
+			return nil
+		}
+		return get(r.info)
+	}}
+}
+
+func one(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return []string{value}
+}
+
+// fields - by lower case name
+var fields = map[string]*field{
+	"identifier":   alertField(func(a *cap.Alert) []string { return one(a.Identifier) }),
+	"sender":       alertField(func(a *cap.Alert) []string { return one(a.Sender) }),
+	"sent":         {values: func(r *record) []string { return one(string(r.alert.Sent)) }, order: timeOrder},
+	"status":       alertField(func(a *cap.Alert) []string { return one(a.Status) }),
+	"msgtype":      alertField(func(a *cap.Alert) []string { return one(a.MsgType) }),
+	"source":       alertField(func(a *cap.Alert) []string { return one(a.Source) }),
+	"scope":        alertField(func(a *cap.Alert) []string { return one(a.Scope) }),
+	"restriction":  alertField(func(a *cap.Alert) []string { return one(a.Restriction) }),
+	"addresses":    alertField(func(a *cap.Alert) []string { return strings.Fields(a.Addresses) }),
+	"code":         alertField(func(a *cap.Alert) []string { return a.Code }),
+	"note":         alertField(func(a *cap.Alert) []string { return one(a.Note) }),
+	"references":   alertField(func(a *cap.Alert) []string { return a.References }),
+	"incidents":    alertField(func(a *cap.Alert) []string { return a.Incidents }),
+	"language":     infoField(func(info *cap.Info) []string { return one(info.Language) }),
+	"category":     infoField(func(info *cap.Info) []string { return info.Category }),
+	"event":        infoField(func(info *cap.Info) []string { return one(info.Event) }),
+	"responsetype": infoField(func(info *cap.Info) []string { return info.ResponseType }),
+	"audience":     infoField(func(info *cap.Info) []string { return one(info.Audience) }),
+	"sendername":   infoField(func(info *cap.Info) []string { return one(info.SenderName) }),
+	"headline":     infoField(func(info *cap.Info) []string { return one(info.Headline) }),
+	"description":  infoField(func(info *cap.Info) []string { return one(info.Description) }),
+	"instruction":  infoField(func(info *cap.Info) []string { return one(info.Instruction) }),
+	"web":          infoField(func(info *cap.Info) []string { return one(info.Web) }),
+	"contact":      infoField(func(info *cap.Info) []string { return one(info.Contact) }),
+	"areadesc": infoField(func(info *cap.Info) []string {
+		var values []string
+		for _, area := range info.Area {
+			values = append(values, one(area.AreaDesc)...)
+		}
+		return values
+	}),
+}
+
+// ordered fields, the ranks of severity, urgency and certainty are ordered
+// from the least to the most serious
+func init() {
+	ordered := map[string]struct {
+		get   func(info *cap.Info) string
+		order *ordering
+	}{
+		"urgency":   {func(info *cap.Info) string { return info.Urgency }, rankOf("Unknown", "Past", "Future", "Expected", "Immediate")},
+		"severity":  {func(info *cap.Info) string { return info.Severity }, rankOf("Unknown", "Minor", "Moderate", "Severe", "Extreme")},
+		"certainty": {func(info *cap.Info) string { return info.Certainty }, rankOf("Unknown", "Unlikely", "Possible", "Likely", "Observed")},
+		"effective": {func(info *cap.Info) string { return string(info.Effective) }, timeOrder},
+		"onset":     {func(info *cap.Info) string { return string(info.Onset) }, timeOrder},
+		"expires":   {func(info *cap.Info) string { return string(info.Expires) }, timeOrder},
+	}
+	for name, o := range ordered {
+		get := o.get
+		f := infoField(func(info *cap.Info) []string { return one(get(info)) })
+		f.order = o.order
+		fields[name] = f
+	}
+}
+
+// named fields, whose name is followed by the name of the value, e.g.
+// geocode.UGC or parameter.VTEC
+var namedFields = map[string]func(r *record, name string) []string{
+	"geocode": func(r *record, name string) []string {
+		if r.info == nil {
+			return nil
+		}
+		var values []string
+		for i := range r.info.Area {
+			values = append(values, namedValues(r.info.Area[i].Geocode, name)...)
+		}
+		return values
+	},
+	"parameter": func(r *record, name string) []string {
+		if r.info == nil {
+			return nil
+		}
+		return namedValues(r.info.Parameter, name)
+	},
+	"eventcode": func(r *record, name string) []string {
+		if r.info == nil {
+			return nil
+		}
+		return namedValues(r.info.EventCode, name)
+	},
+}
+
+func namedValues(pairs []cap.NamedValue, name string) []string {
+	var values []string
+	for _, pair := range pairs {
+		if strings.EqualFold(pair.ValueName, name) {
+			values = append(values, pair.Value)
+		}
+	}
+	return values
+}
+
+// lookupField returns the field named name, field names are case insensitive
+func lookupField(name string) (*field, error) {
+	lower := strings.ToLower(name)
+	if f, ok := fields[lower]; ok {
+		return f, nil
+	}
+	if i := strings.Index(lower, "."); i > 0 {
+		if get, ok := namedFields[lower[:i]]; ok {
+			valueName := name[i+1:]
+			if valueName == "" {
+				return nil, fmt.Errorf("missing value name after %q, e.g. %sUGC", name, name)
+			}
+			return &field{values: func(r *record) []string { return get(r, valueName) }}, nil
+		}
+	}
+	if suggestion := closestField(lower); suggestion != "" {
+		return nil, fmt.Errorf("unknown field %q, did you mean %q", name, suggestion)
+	}
+	return nil, fmt.Errorf("unknown field %q", name)
+}
+
+// closestField returns the field name within two edits of name, or ""
+func closestField(name string) string {
+	best, bestDistance := "", 3
+	for candidate := range fields {
+		if d := editDistance(name, candidate); d < bestDistance || (d == bestDistance && candidate < best) {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
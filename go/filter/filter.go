@@ -0,0 +1,190 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filter
+
+import (
+	"strings"
+
+	"github.com/IBM/cap/go/atom"
+	"github.com/IBM/cap/go/cap"
+)
+
+// Filter - a compiled filter expression, safe for concurrent use
+type Filter struct {
+	expr string
+	root node
+}
+
+// Compile parses a filter expression such as
+//
+//	severity in (Severe, Extreme) and urgency = Immediate and event ~ "flood" and geocode.UGC startswith "TX"
+//
+// Comparisons of a field with a value are combined with and, or, not and
+// parentheses. The operators are
+//
+//	=, !=                  equal, not equal
+//	~                      contains
+//	startswith, endswith   starts or ends with
+//	in (a, b), not in (a, b)
+//	<, <=, >, >=           for urgency, severity and certainty by rank, and times
+//
+// Values are quoted with " or ' when they contain spaces, punctuation or are
+// one of the keywords. Text comparisons and field names are case insensitive.
+//
+// The fields are those of the CAP alert and info elements, e.g. identifier,
+// sender, sent, status, msgType, scope, category, event, urgency, severity,
+// certainty, effective, onset, expires, headline, description and areaDesc,
+// and the named values geocode.NAME, parameter.NAME and eventCode.NAME. A
+// comparison holds when it holds for any value of a field with several values,
+// != and not in hold when no value is equal. A *SyntaxError locating the
+// problem is returned for invalid expressions.
+func Compile(expr string) (*Filter, error) {
+	root, err := parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{expr: expr, root: root}, nil
+}
+
+// MustCompile is like Compile but panics if the expression is invalid
+func MustCompile(expr string) *Filter {
+	f, err := Compile(expr)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// String returns the expression the filter was compiled from
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// Match reports whether the alert matches the filter. The filter is evaluated
+// for each info of the alert, with the alert's own fields, and matches if it
+// holds for any of them so fields of different infos are never combined.
+func (f *Filter) Match(alert *cap.Alert) bool {
+	if len(alert.Info) == 0 {
+		return f.root.eval(&record{alert: alert})
+	}
+	for i := range alert.Info {
+		if f.root.eval(&record{alert: alert, info: &alert.Info[i]}) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchEntry reports whether the alert summarized by the entry matches the
+// filter, see Entry.ToAlert for how entry elements map to alert fields
+func (f *Filter) MatchEntry(entry *atom.Entry) bool {
+	return f.Match(entry.ToAlert())
+}
+
+type node interface {
+	eval(r *record) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(r *record) bool { return n.left.eval(r) && n.right.eval(r) }
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(r *record) bool { return n.left.eval(r) || n.right.eval(r) }
+
+type notNode struct{ node node }
+
+func (n notNode) eval(r *record) bool { return !n.node.eval(r) }
+
+// textNode - a case insensitive comparison of text
+type textNode struct {
+	field *field
+	op    string
+	value string
+}
+
+func (n textNode) eval(r *record) bool {
+	want := strings.ToLower(n.value)
+	for _, value := range n.field.values(r) {
+		value = strings.ToLower(strings.TrimSpace(value))
+		var ok bool
+		switch n.op {
+		case "=":
+			ok = value == want
+		case "~":
+			ok = strings.Contains(value, want)
+		case "startswith":
+			ok = strings.HasPrefix(value, want)
+		case "endswith":
+			ok = strings.HasSuffix(value, want)
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+type inNode struct {
+	field  *field
+	values []string
+}
+
+func (n inNode) eval(r *record) bool {
+	for _, value := range n.field.values(r) {
+		value = strings.TrimSpace(value)
+		for _, want := range n.values {
+			if strings.EqualFold(value, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// orderedNode - a comparison of ranks or times, values which cannot be ranked
+// or parsed never match
+type orderedNode struct {
+	field *field
+	op    string
+	key   int64
+}
+
+func (n orderedNode) eval(r *record) bool {
+	for _, value := range n.field.values(r) {
+		key, err := n.field.order.key(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		var ok bool
+		switch n.op {
+		case "<":
+			ok = key < n.key
+		case "<=":
+			ok = key <= n.key
+		case ">":
+			ok = key > n.key
+		case ">=":
+			ok = key >= n.key
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
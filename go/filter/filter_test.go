@@ -0,0 +1,143 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filter
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"testing"
+
+	"github.com/IBM/cap/go/atom"
+	"github.com/IBM/cap/go/cap"
+	"github.com/stretchr/testify/assert"
+)
+
+func getAmberAlert(t *testing.T) *cap.Alert {
+	xmlData, err := ioutil.ReadFile("../../resources/cap_amber_alert_example.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alert, err := cap.ParseAlert(xmlData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return alert
+}
+
+func getNwsEntry(t *testing.T) *atom.Entry {
+	xmlData, err := ioutil.ReadFile("../../resources/nws_atom_feed_example.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feed atom.Feed
+	if err := xml.Unmarshal(xmlData, &feed); err != nil {
+		t.Fatal(err)
+	}
+	return &feed.Entries[0]
+}
+
+func TestFilterMatchesAlert(t *testing.T) {
+	alert := getAmberAlert(t)
+	for expr, want := range map[string]bool{
+		`severity in (Severe, Extreme) and urgency = Immediate`:              true,
+		`severity in (Moderate, Minor)`:                                      false,
+		`severity not in (Moderate, Minor)`:                                  true,
+		`event ~ "abduction" and category = rescue`:                          true,
+		`event = "Child"`:                                                    false,
+		`event startswith child and event endswith ABDUCTION`:                true,
+		`geocode.SAME = 006037`:                                              true,
+		`geocode.same startswith "06"`:                                       false,
+		`eventCode.SAME = CAE`:                                               true,
+		`parameter.VTEC ~ HW`:                                                false,
+		`parameter.VTEC != "x"`:                                              true,
+		`severity >= Severe and certainty > possible`:                        true,
+		`severity > Severe or urgency < Expected`:                            false,
+		`sent < 2003-06-12T00:00:00-07:00 and sent > "2003-06-11T00:00:00Z"`: true,
+		`not (status = Actual) or msgType = Cancel`:                          false,
+		`(status = Test or status = Actual) and (scope = Public)`:            true,
+		`identifier = "KAR0-0306112239-SW"`:                                  true,
+	} {
+		f, err := Compile(expr)
+		if !assert.Nil(t, err, expr) {
+			continue
+		}
+		assert.Equal(t, want, f.Match(alert), expr)
+	}
+}
+
+func TestFilterEvaluatesEachInfo(t *testing.T) {
+	alert := getAmberAlert(t)
+	alert.Info[0].Language = "en-US"
+	alert.Info[1].Language = "es-US"
+	// the Spanish event is only in the Spanish info
+	assert.False(t, MustCompile(`language = en-US and event ~ "Niño"`).Match(alert))
+	assert.True(t, MustCompile(`language = es-US and event ~ "Niño"`).Match(alert))
+
+	alert.Info = nil
+	assert.True(t, MustCompile(`status = Actual`).Match(alert))
+	assert.False(t, MustCompile(`severity = Severe`).Match(alert))
+}
+
+func TestFilterMatchesEntry(t *testing.T) {
+	entry := getNwsEntry(t)
+	assert.True(t, MustCompile(`severity in (Severe, Extreme) and urgency = Expected and event ~ "wind" and geocode.UGC startswith "AK"`).MatchEntry(entry))
+	assert.True(t, MustCompile(`geocode.FIPS6 = 002185 and category = Met and expires > 2018-08-16T00:00:00Z`).MatchEntry(entry))
+	assert.True(t, MustCompile(`sender = "w-nws.webmaster@noaa.gov" and headline ~ "High Wind"`).MatchEntry(entry))
+	assert.False(t, MustCompile(`geocode.UGC startswith "TX"`).MatchEntry(entry))
+}
+
+func TestCompileReportsSyntaxErrors(t *testing.T) {
+	for expr, message := range map[string]string{
+		``:                              `filter: empty filter at column 1`,
+		`severty = Severe`:              `filter: unknown field "severty", did you mean "severity" at column 1`,
+		`colour = red`:                  `filter: unknown field "colour" at column 1`,
+		`geocode. = TX`:                 `filter: missing value name after "geocode.", e.g. geocode.UGC at column 1`,
+		`severity Severe`:               `filter: unexpected "Severe" after "severity", expected an operator such as =, !=, ~, in or startswith at column 10`,
+		`severity =`:                    `filter: unexpected end of filter, expected a value after "=" at column 11`,
+		`severity = and`:                `filter: unexpected "and", expected a value after "=" at column 12`,
+		`severity in Severe`:            `filter: unexpected "Severe", expected "(" after "in" at column 13`,
+		`severity in (Severe Extreme)`:  `filter: unexpected "Extreme", expected "," or ")" in the list of values at column 21`,
+		`(severity = Severe`:            `filter: expected ")" to close the "(" opened at column 1, found end of filter at column 19`,
+		`severity = Severe urgency = x`: `filter: unexpected "urgency", expected "and", "or" or end of filter at column 19`,
+		`event ~ "flood`:                `filter: unterminated string at column 9`,
+		`event ! flood`:                 `filter: unexpected "!", did you mean "!=" at column 7`,
+		`event & flood`:                 `filter: unexpected character '&' at column 7`,
+		`event > flood`:                 `filter: field "event" cannot be compared with >, only severity, urgency, certainty and times can at column 7`,
+		`severity >= Bad`:               `filter: invalid value "Bad", expected one of Unknown, Minor, Moderate, Severe, Extreme for "severity" at column 13`,
+		`expires < tomorrow`:            `filter: invalid time "tomorrow", expected a time like 2018-08-15T14:52:00-08:00 for "expires" at column 11`,
+		`and = 1`:                       `filter: unexpected "and", expected a field name at column 1`,
+	} {
+		_, err := Compile(expr)
+		if assert.NotNil(t, err, expr) {
+			assert.Equal(t, message, err.Error(), expr)
+		}
+	}
+}
+
+func TestSyntaxErrorPointer(t *testing.T) {
+	_, err := Compile(`event ~ "Niño" and severty = Severe`)
+	syntaxErr, ok := err.(*SyntaxError)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, "event ~ \"Niño\" and severty = Severe\n                   ^", syntaxErr.Pointer())
+}
+
+func TestFilterString(t *testing.T) {
+	assert.Equal(t, "severity = Severe", MustCompile("severity = Severe").String())
+	assert.Panics(t, func() { MustCompile("severity =") })
+}
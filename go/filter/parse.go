@@ -0,0 +1,350 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SyntaxError - an error in a filter expression
+type SyntaxError struct {
+	Expr   string // Expr - the expression
+	Offset int    // Offset - the byte offset of the error in Expr
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("filter: %s at column %d", e.Msg, column(e.Expr, e.Offset))
+}
+
+// Pointer returns the expression with a line below marking the error
+func (e *SyntaxError) Pointer() string {
+	return e.Expr + "\n" + strings.Repeat(" ", column(e.Expr, e.Offset)-1) + "^"
+}
+
+// column returns the column, counted in characters from 1, of the offset
+func column(expr string, offset int) int {
+	return utf8.RuneCountInString(expr[:offset]) + 1
+}
+
+type tokenKind int
+
+const (
+	tokenEOF    tokenKind = iota
+	tokenWord             // tokenWord - a field name, keyword or unquoted value
+	tokenString           // tokenString - a quoted value
+	tokenPunct            // tokenPunct - ( ) , or a comparison operator
+)
+
+type token struct {
+	kind   tokenKind
+	text   string // text - the word, the unquoted string or the punctuation
+	offset int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of filter"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// is reports whether the token is the keyword or punctuation, keywords are
+// case insensitive
+func (t token) is(text string) bool {
+	return (t.kind == tokenWord || t.kind == tokenPunct) && strings.EqualFold(t.text, text)
+}
+
+// lex splits expr into tokens
+func lex(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		r, size := utf8.DecodeRuneInString(expr[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '"' || r == '\'':
+			text, end, err := lexString(expr, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, offset: i})
+			i = end
+		case strings.ContainsRune("(),~", r):
+			tokens = append(tokens, token{kind: tokenPunct, text: string(r), offset: i})
+			i++
+		case strings.ContainsRune("=!<>", r):
+			end := i + 1
+			if end < len(expr) && expr[end] == '=' {
+				end++
+			}
+			op := expr[i:end]
+			if op == "!" {
+				return nil, &SyntaxError{Expr: expr, Offset: i, Msg: `unexpected "!", did you mean "!="`}
+			}
+			if op == "==" {
+				op = "="
+			}
+			tokens = append(tokens, token{kind: tokenPunct, text: op, offset: i})
+			i = end
+		case isWordRune(r):
+			end := i
+			for end < len(expr) {
+				r, size := utf8.DecodeRuneInString(expr[end:])
+				if !isWordRune(r) {
+					break
+				}
+				end += size
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: expr[i:end], offset: i})
+			i = end
+		default:
+			return nil, &SyntaxError{Expr: expr, Offset: i, Msg: fmt.Sprintf("unexpected character %q", r)}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, offset: len(expr)}), nil
+}
+
+// isWordRune - whether r may appear in an unquoted word, which covers field
+// names like geocode.UGC and values like TXZ213 or 2018-08-15T14:52:00-08:00
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_.:-+/@", r)
+}
+
+// lexString returns the unquoted string starting at expr[start] and the offset
+// after its closing quote, backslash escapes the next character
+func lexString(expr string, start int) (string, int, error) {
+	quote := expr[start]
+	var text strings.Builder
+	for i := start + 1; i < len(expr); i++ {
+		switch expr[i] {
+		case quote:
+			return text.String(), i + 1, nil
+		case '\\':
+			if i+1 < len(expr) {
+				i++
+			}
+		}
+		text.WriteByte(expr[i])
+	}
+	return "", 0, &SyntaxError{Expr: expr, Offset: start, Msg: "unterminated string"}
+}
+
+// parser - a recursive descent parser of the grammar
+//
+//	expr       = and { "or" and }
+//	and        = unary { "and" unary }
+//	unary      = "not" unary | "(" expr ")" | comparison
+//	comparison = field operator value | field [ "not" ] "in" "(" value { "," value } ")"
+//	operator   = "=" | "!=" | "~" | "<" | "<=" | ">" | ">=" | "startswith" | "endswith"
+type parser struct {
+	expr   string
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return &SyntaxError{Expr: p.expr, Offset: t.offset, Msg: fmt.Sprintf(format, args...)}
+}
+
+func parse(expr string) (node, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{expr: expr, tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, p.errorf(p.peek(), "empty filter")
+	}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.errorf(t, "unexpected %s, expected \"and\", \"or\" or end of filter", t)
+	}
+	return n, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().is("and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	t := p.peek()
+	switch {
+	case t.is("not"):
+		p.next()
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	case t.is("("):
+		p.next()
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); !closing.is(")") {
+			return nil, p.errorf(closing, "expected \")\" to close the \"(\" opened at column %d, found %s", column(p.expr, t.offset), closing)
+		}
+		return n, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	name := p.next()
+	if name.kind != tokenWord || isKeyword(name.text) {
+		return nil, p.errorf(name, "unexpected %s, expected a field name", name)
+	}
+	f, err := lookupField(name.text)
+	if err != nil {
+		return nil, p.errorf(name, "%v", err)
+	}
+	op := p.next()
+	negate := false
+	if op.is("not") && p.peek().is("in") {
+		negate = true
+		op = p.next()
+	}
+	var n node
+	switch {
+	case op.is("in"):
+		n, err = p.parseIn(f)
+	case op.is("=") || op.is("!=") || op.is("~") || op.is("startswith") || op.is("endswith"):
+		n, err = p.parseText(f, op)
+	case op.is("<") || op.is("<=") || op.is(">") || op.is(">="):
+		n, err = p.parseOrdered(f, name, op)
+	default:
+		return nil, p.errorf(op, "unexpected %s after %q, expected an operator such as =, !=, ~, in or startswith", op, name.text)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if negate {
+		n = notNode{n}
+	}
+	return n, nil
+}
+
+func (p *parser) parseValue(after token) (token, error) {
+	value := p.next()
+	if value.kind == tokenString || (value.kind == tokenWord && !isKeyword(value.text)) {
+		return value, nil
+	}
+	return value, p.errorf(value, "unexpected %s, expected a value after %q", value, after.text)
+}
+
+func (p *parser) parseText(f *field, op token) (node, error) {
+	value, err := p.parseValue(op)
+	if err != nil {
+		return nil, err
+	}
+	if op.text == "!=" {
+		return notNode{textNode{field: f, op: "=", value: value.text}}, nil
+	}
+	return textNode{field: f, op: strings.ToLower(op.text), value: value.text}, nil
+}
+
+func (p *parser) parseIn(f *field) (node, error) {
+	open := p.next()
+	if !open.is("(") {
+		return nil, p.errorf(open, "unexpected %s, expected \"(\" after \"in\"", open)
+	}
+	var values []string
+	for previous := open; ; {
+		value, err := p.parseValue(previous)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value.text)
+		separator := p.next()
+		previous = separator
+		if separator.is(")") {
+			return inNode{field: f, values: values}, nil
+		}
+		if !separator.is(",") {
+			return nil, p.errorf(separator, "unexpected %s, expected \",\" or \")\" in the list of values", separator)
+		}
+	}
+}
+
+func (p *parser) parseOrdered(f *field, name token, op token) (node, error) {
+	if f.order == nil {
+		return nil, p.errorf(op, "field %q cannot be compared with %s, only severity, urgency, certainty and times can", name.text, op.text)
+	}
+	value, err := p.parseValue(op)
+	if err != nil {
+		return nil, err
+	}
+	key, err := f.order.key(value.text)
+	if err != nil {
+		return nil, p.errorf(value, "%v for %q", err, name.text)
+	}
+	return orderedNode{field: f, op: op.text, key: key}, nil
+}
+
+var keywords = map[string]bool{"and": true, "or": true, "not": true, "in": true, "startswith": true, "endswith": true}
+
+func isKeyword(word string) bool {
+	return keywords[strings.ToLower(word)]
+}
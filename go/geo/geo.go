@@ -0,0 +1,340 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package geo
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/IBM/cap/go/cap"
+)
+
+// EarthRadius is the mean radius of the earth in kilometers
+const EarthRadius float64 = 6371.0088
+
+// Point - a WGS 84 coordinate in decimal degrees
+type Point struct {
+	Lat float64
+	Lon float64
+}
+
+// BBox - a bounding box in decimal degrees
+type BBox struct {
+	MinLat float64
+	MinLon float64
+	MaxLat float64
+	MaxLon float64
+}
+
+// Shape - a geometry which can be placed in an Index
+type Shape interface {
+	Bounds() BBox           // Bounds - the smallest BBox enclosing the shape
+	Contains(p Point) bool  // Contains - whether the point lies inside the shape
+	Intersects(b BBox) bool // Intersects - whether the shape and the box overlap
+	String() string         // String - the shape in the CAP text format
+}
+
+// Polygon - a closed ring of points, as in a CAP area polygon element
+type Polygon []Point
+
+// Circle - a center point and radius in kilometers, as in a CAP area circle element
+type Circle struct {
+	Center Point
+	Radius float64
+}
+
+// ParsePoint parses a CAP "lat,lon" coordinate pair
+func ParsePoint(s string) (Point, error) {
+	pair := strings.Split(strings.TrimSpace(s), ",")
+	if len(pair) != 2 {
+		return Point{}, fmt.Errorf("invalid coordinate pair %q", s)
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(pair[0]), 64)
+	if err != nil {
+		return Point{}, fmt.Errorf("invalid latitude %q", pair[0])
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(pair[1]), 64)
+	if err != nil {
+		return Point{}, fmt.Errorf("invalid longitude %q", pair[1])
+	}
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return Point{}, fmt.Errorf("coordinate pair %q out of range", s)
+	}
+	return Point{Lat: lat, Lon: lon}, nil
+}
+
+// ParsePolygon parses a CAP polygon, a whitespace delimited list of at least
+// four "lat,lon" pairs where the first and last pairs are equal
+func ParsePolygon(s string) (Polygon, error) {
+	fields := strings.Fields(s)
+	polygon := make(Polygon, 0, len(fields))
+	for _, field := range fields {
+		point, err := ParsePoint(field)
+		if err != nil {
+			return nil, err
+		}
+		polygon = append(polygon, point)
+	}
+	if len(polygon) < 4 {
+		return nil, fmt.Errorf("polygon has %d points, at least 4 are required", len(polygon))
+	}
+	if polygon[0] != polygon[len(polygon)-1] {
+		// be lenient with publishers which do not close the ring
+		polygon = append(polygon, polygon[0])
+	}
+	return polygon, nil
+}
+
+// ParseCircle parses a CAP circle, a "lat,lon" pair followed by a radius in kilometers
+func ParseCircle(s string) (Circle, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return Circle{}, fmt.Errorf("invalid circle %q", s)
+	}
+	center, err := ParsePoint(fields[0])
+	if err != nil {
+		return Circle{}, err
+	}
+	radius, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || radius < 0 {
+		return Circle{}, fmt.Errorf("invalid circle radius %q", fields[1])
+	}
+	return Circle{Center: center, Radius: radius}, nil
+}
+
+// AreaShapes returns the polygons and circles of a CAP area, empty polygon and
+// circle elements (as sent in the NWS Atom feed) are skipped
+func AreaShapes(area *cap.Area) ([]Shape, error) {
+	var shapes []Shape
+	for _, p := range area.Polygon {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		polygon, err := ParsePolygon(p)
+		if err != nil {
+			return nil, err
+		}
+		shapes = append(shapes, polygon)
+	}
+	for _, c := range area.Circle {
+		if strings.TrimSpace(c) == "" {
+			continue
+		}
+		circle, err := ParseCircle(c)
+		if err != nil {
+			return nil, err
+		}
+		shapes = append(shapes, circle)
+	}
+	return shapes, nil
+}
+
+// AlertShapes returns the shapes of all of the areas of all of the infos of an alert
+func AlertShapes(alert *cap.Alert) ([]Shape, error) {
+	var shapes []Shape
+	for i := range alert.Info {
+		for j := range alert.Info[i].Area {
+			found, err := AreaShapes(&alert.Info[i].Area[j])
+			if err != nil {
+				return nil, err
+			}
+			shapes = append(shapes, found...)
+		}
+	}
+	return shapes, nil
+}
+
+// Bounds - the smallest BBox enclosing the polygon
+func (p Polygon) Bounds() BBox {
+	b := emptyBBox()
+	for _, point := range p {
+		b = b.extend(point)
+	}
+	return b
+}
+
+// Contains - whether the point lies inside the polygon, using the even-odd rule
+func (p Polygon) Contains(point Point) bool {
+	if !p.Bounds().Contains(point) {
+		return false
+	}
+	inside := false
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		a, b := p[i], p[j]
+		if (a.Lat > point.Lat) != (b.Lat > point.Lat) &&
+			point.Lon < (b.Lon-a.Lon)*(point.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// Intersects - whether the polygon and the box overlap
+func (p Polygon) Intersects(b BBox) bool {
+	if !p.Bounds().Intersects(b) {
+		return false
+	}
+	for _, point := range p {
+		if b.Contains(point) {
+			return true
+		}
+	}
+	corners := b.corners()
+	for _, corner := range corners {
+		if p.Contains(corner) {
+			return true
+		}
+	}
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		for k, l := 0, len(corners)-1; k < len(corners); l, k = k, k+1 {
+			if segmentsIntersect(p[j], p[i], corners[l], corners[k]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String - the polygon in the CAP "lat,lon lat,lon ..." format
+func (p Polygon) String() string {
+	pairs := make([]string, len(p))
+	for i, point := range p {
+		pairs[i] = point.String()
+	}
+	return strings.Join(pairs, " ")
+}
+
+// Bounds - the smallest BBox enclosing the circle
+func (c Circle) Bounds() BBox {
+	dLat := c.Radius / EarthRadius * 180 / math.Pi
+	minLat := math.Max(c.Center.Lat-dLat, -90)
+	maxLat := math.Min(c.Center.Lat+dLat, 90)
+	if minLat == -90 || maxLat == 90 {
+		return BBox{MinLat: minLat, MinLon: -180, MaxLat: maxLat, MaxLon: 180}
+	}
+	dLon := dLat / math.Cos(c.Center.Lat*math.Pi/180)
+	return BBox{
+		MinLat: minLat,
+		MinLon: math.Max(c.Center.Lon-dLon, -180),
+		MaxLat: maxLat,
+		MaxLon: math.Min(c.Center.Lon+dLon, 180),
+	}
+}
+
+// Contains - whether the point lies within the radius of the circle's center
+func (c Circle) Contains(point Point) bool {
+	return Haversine(c.Center, point) <= c.Radius
+}
+
+// Intersects - whether the circle and the box overlap
+func (c Circle) Intersects(b BBox) bool {
+	if !c.Bounds().Intersects(b) {
+		return false
+	}
+	nearest := Point{
+		Lat: math.Max(b.MinLat, math.Min(c.Center.Lat, b.MaxLat)),
+		Lon: math.Max(b.MinLon, math.Min(c.Center.Lon, b.MaxLon)),
+	}
+	return c.Contains(nearest)
+}
+
+// String - the circle in the CAP "lat,lon radius" format
+func (c Circle) String() string {
+	return c.Center.String() + " " + strconv.FormatFloat(c.Radius, 'f', -1, 64)
+}
+
+// String - the point in the CAP "lat,lon" format
+func (p Point) String() string {
+	return strconv.FormatFloat(p.Lat, 'f', -1, 64) + "," + strconv.FormatFloat(p.Lon, 'f', -1, 64)
+}
+
+// Contains - whether the point lies inside or on the edge of the box
+func (b BBox) Contains(p Point) bool {
+	return p.Lat >= b.MinLat && p.Lat <= b.MaxLat && p.Lon >= b.MinLon && p.Lon <= b.MaxLon
+}
+
+// Intersects - whether the two boxes overlap
+func (b BBox) Intersects(o BBox) bool {
+	return b.MinLat <= o.MaxLat && o.MinLat <= b.MaxLat && b.MinLon <= o.MaxLon && o.MinLon <= b.MaxLon
+}
+
+// Union - the smallest box enclosing both boxes
+func (b BBox) Union(o BBox) BBox {
+	return BBox{
+		MinLat: math.Min(b.MinLat, o.MinLat),
+		MinLon: math.Min(b.MinLon, o.MinLon),
+		MaxLat: math.Max(b.MaxLat, o.MaxLat),
+		MaxLon: math.Max(b.MaxLon, o.MaxLon),
+	}
+}
+
+func (b BBox) containsBBox(o BBox) bool {
+	return o.MinLat >= b.MinLat && o.MaxLat <= b.MaxLat && o.MinLon >= b.MinLon && o.MaxLon <= b.MaxLon
+}
+
+func (b BBox) area() float64 {
+	return (b.MaxLat - b.MinLat) * (b.MaxLon - b.MinLon)
+}
+
+func (b BBox) extend(p Point) BBox {
+	return b.Union(BBox{MinLat: p.Lat, MinLon: p.Lon, MaxLat: p.Lat, MaxLon: p.Lon})
+}
+
+func (b BBox) corners() []Point {
+	return []Point{
+		{Lat: b.MinLat, Lon: b.MinLon},
+		{Lat: b.MinLat, Lon: b.MaxLon},
+		{Lat: b.MaxLat, Lon: b.MaxLon},
+		{Lat: b.MaxLat, Lon: b.MinLon},
+	}
+}
+
+func emptyBBox() BBox {
+	return BBox{MinLat: math.Inf(1), MinLon: math.Inf(1), MaxLat: math.Inf(-1), MaxLon: math.Inf(-1)}
+}
+
+// Haversine returns the great circle distance between two points in kilometers
+func Haversine(a Point, b Point) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Lon - a.Lon) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func segmentsIntersect(p1 Point, p2 Point, q1 Point, q2 Point) bool {
+	d1 := orientation(q1, q2, p1)
+	d2 := orientation(q1, q2, p2)
+	d3 := orientation(p1, p2, q1)
+	d4 := orientation(p1, p2, q2)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	return (d1 == 0 && onSegment(q1, q2, p1)) || (d2 == 0 && onSegment(q1, q2, p2)) ||
+		(d3 == 0 && onSegment(p1, p2, q1)) || (d4 == 0 && onSegment(p1, p2, q2))
+}
+
+func orientation(a Point, b Point, c Point) float64 {
+	return (b.Lon-a.Lon)*(c.Lat-a.Lat) - (b.Lat-a.Lat)*(c.Lon-a.Lon)
+}
+
+func onSegment(a Point, b Point, p Point) bool {
+	return math.Min(a.Lat, b.Lat) <= p.Lat && p.Lat <= math.Max(a.Lat, b.Lat) &&
+		math.Min(a.Lon, b.Lon) <= p.Lon && p.Lon <= math.Max(a.Lon, b.Lon)
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package geo
+
+import (
+	"testing"
+
+	"github.com/IBM/cap/go/cap"
+	"github.com/stretchr/testify/assert"
+)
+
+// from the first polygon in resources/nws_atom_feed_example.xml
+const examplePolygon = "31.62,-86.35 31.59,-86.5 31.96,-86.8 31.96,-86.41 32.03,-86.41 32.04,-86.4 32.05,-86.41 32.05,-86.4 31.62,-86.35"
+
+func TestParsePolygonHasProperValues(t *testing.T) {
+	polygon, err := ParsePolygon(examplePolygon)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 9, len(polygon))
+	assert.Equal(t, Point{Lat: 31.62, Lon: -86.35}, polygon[0])
+	assert.Equal(t, BBox{MinLat: 31.59, MinLon: -86.8, MaxLat: 32.05, MaxLon: -86.35}, polygon.Bounds())
+	assert.Equal(t, examplePolygon, polygon.String())
+}
+
+func TestParsePolygonClosesRing(t *testing.T) {
+	polygon, err := ParsePolygon("0,0 0,1 1,1 1,0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 5, len(polygon))
+	assert.Equal(t, polygon[0], polygon[4])
+}
+
+func TestParsePolygonReturnsErrForInvalidValues(t *testing.T) {
+	_, err := ParsePolygon("0,0 0,1 0,0")
+	assert.Equal(t, "polygon has 3 points, at least 4 are required", err.Error())
+	_, err = ParsePolygon("0,0 0,1 1,x 0,0")
+	assert.Equal(t, "invalid longitude \"x\"", err.Error())
+	_, err = ParsePolygon("0,0 0,1 91,1 0,0")
+	assert.Equal(t, "coordinate pair \"91,1\" out of range", err.Error())
+}
+
+func TestParseCircleHasProperValues(t *testing.T) {
+	circle, err := ParseCircle("32.9525,-115.5527 10.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, Circle{Center: Point{Lat: 32.9525, Lon: -115.5527}, Radius: 10.5}, circle)
+	assert.Equal(t, "32.9525,-115.5527 10.5", circle.String())
+	_, err = ParseCircle("32.9525,-115.5527")
+	assert.Error(t, err)
+}
+
+func TestPolygonContains(t *testing.T) {
+	polygon, _ := ParsePolygon(examplePolygon)
+	assert.True(t, polygon.Contains(Point{Lat: 31.8, Lon: -86.5}))
+	assert.False(t, polygon.Contains(Point{Lat: 31.65, Lon: -86.75}))
+	assert.False(t, polygon.Contains(Point{Lat: 40, Lon: -86.5}))
+}
+
+func TestPolygonIntersects(t *testing.T) {
+	polygon, _ := ParsePolygon("0,0 0,10 10,10 10,0 0,0")
+	assert.True(t, polygon.Intersects(BBox{MinLat: 2, MinLon: 2, MaxLat: 3, MaxLon: 3}))     // box inside
+	assert.True(t, polygon.Intersects(BBox{MinLat: -5, MinLon: -5, MaxLat: 15, MaxLon: 15})) // polygon inside
+	assert.True(t, polygon.Intersects(BBox{MinLat: -1, MinLon: 4, MaxLat: 11, MaxLon: 5}))   // crossing edges
+	assert.False(t, polygon.Intersects(BBox{MinLat: 11, MinLon: 11, MaxLat: 12, MaxLon: 12}))
+
+	triangle, _ := ParsePolygon("0,0 10,10 0,10 0,0")
+	assert.False(t, triangle.Intersects(BBox{MinLat: 6, MinLon: 1, MaxLat: 9, MaxLon: 4}))
+}
+
+func TestCircleContainsAndIntersects(t *testing.T) {
+	circle := Circle{Center: Point{Lat: 45, Lon: -100}, Radius: 50}
+	assert.True(t, circle.Contains(Point{Lat: 45.4, Lon: -100}))
+	assert.False(t, circle.Contains(Point{Lat: 45.5, Lon: -100}))
+	assert.True(t, circle.Bounds().Contains(Point{Lat: 45.4, Lon: -100.6}))
+	assert.True(t, circle.Intersects(BBox{MinLat: 45.3, MinLon: -100.1, MaxLat: 46, MaxLon: -99.9}))
+	assert.False(t, circle.Intersects(BBox{MinLat: 45.4, MinLon: -99.5, MaxLat: 46, MaxLon: -99}))
+}
+
+func TestAreaShapesSkipsEmptyElements(t *testing.T) {
+	area := cap.Area{Polygon: []string{""}, Circle: []string{"45,-100 5"}}
+	shapes, err := AreaShapes(&area)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(shapes))
+	assert.Equal(t, "45,-100 5", shapes[0].String())
+}
+
+func TestHaversine(t *testing.T) {
+	// one degree of latitude is roughly 111 kilometers
+	assert.InDelta(t, 111.2, Haversine(Point{Lat: 0, Lon: 0}, Point{Lat: 1, Lon: 0}), 0.1)
+}
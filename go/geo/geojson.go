@@ -0,0 +1,82 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package geo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var errUnsupportedGeometry = errors.New("unsupported GeoJSON geometry type")
+
+// ParseGeoJSONGeometry parses a GeoJSON Polygon or MultiPolygon geometry object
+func ParseGeoJSONGeometry(data []byte) (MultiPolygon, error) {
+	var geometry struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}
+	if err := json.Unmarshal(data, &geometry); err != nil {
+		return nil, err
+	}
+	switch geometry.Type {
+	case "Polygon":
+		var coordinates [][][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &coordinates); err != nil {
+			return nil, err
+		}
+		rings, err := geoJSONRings(coordinates)
+		if err != nil {
+			return nil, err
+		}
+		return MultiPolygon{rings}, nil
+	case "MultiPolygon":
+		var coordinates [][][][]float64
+		if err := json.Unmarshal(geometry.Coordinates, &coordinates); err != nil {
+			return nil, err
+		}
+		multi := make(MultiPolygon, 0, len(coordinates))
+		for _, polygon := range coordinates {
+			rings, err := geoJSONRings(polygon)
+			if err != nil {
+				return nil, err
+			}
+			multi = append(multi, rings)
+		}
+		return multi, nil
+	}
+	return nil, errUnsupportedGeometry
+}
+
+// geoJSONRings converts GeoJSON linear rings, which are [lon, lat] ordered
+func geoJSONRings(coordinates [][][]float64) ([]Polygon, error) {
+	rings := make([]Polygon, 0, len(coordinates))
+	for _, ring := range coordinates {
+		if len(ring) < 4 {
+			return nil, fmt.Errorf("linear ring has %d positions, at least 4 are required", len(ring))
+		}
+		polygon := make(Polygon, 0, len(ring))
+		for _, position := range ring {
+			if len(position) < 2 {
+				return nil, fmt.Errorf("invalid position %v", position)
+			}
+			polygon = append(polygon, Point{Lat: position[1], Lon: position[0]})
+		}
+		rings = append(rings, polygon)
+	}
+	return rings, nil
+}
@@ -0,0 +1,363 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package geo
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/IBM/cap/go/cap"
+)
+
+// ErrNoGeometry is returned when inserting an alert that has no shapes
+var ErrNoGeometry = errors.New("alert has no polygon, circle or resolvable geocode")
+
+// Index - an in-memory spatial index of alerts, keyed by alert identifier. The
+// bounding boxes of the alert's shapes are kept in an R-tree and candidate
+// alerts are refined by exact containment. An Index is safe for concurrent use.
+type Index struct {
+	// Resolver - if set, is used to find the shapes of areas which have no
+	// polygon or circle from their geocodes. Set it before using the Index.
+	Resolver Resolver
+
+	mu     sync.RWMutex
+	tree   rtree
+	alerts map[string][]*indexItem
+}
+
+// indexItem - a single shape of an indexed alert
+type indexItem struct {
+	alert *cap.Alert
+	shape Shape
+	bbox  BBox
+}
+
+// NewIndex creates an empty Index
+func NewIndex() *Index {
+	return &Index{alerts: make(map[string][]*indexItem)}
+}
+
+// Insert adds the alert to the index, replacing any alert with the same
+// identifier. ErrNoGeometry is returned, and nothing is indexed, when the
+// alert has no polygon or circle and none of its geocodes can be resolved.
+func (idx *Index) Insert(alert *cap.Alert) error {
+	shapes, err := ResolveAlertShapes(alert, idx.Resolver)
+	if err != nil {
+		return err
+	}
+	if len(shapes) == 0 {
+		return ErrNoGeometry
+	}
+	return idx.InsertShapes(alert, shapes)
+}
+
+// InsertShapes adds the alert to the index using the provided shapes instead
+// of the alert's own polygons and circles, replacing any alert with the same
+// identifier
+func (idx *Index) InsertShapes(alert *cap.Alert, shapes []Shape) error {
+	if len(shapes) == 0 {
+		return ErrNoGeometry
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(alert.Identifier)
+	items := make([]*indexItem, len(shapes))
+	for i, shape := range shapes {
+		items[i] = &indexItem{alert: alert, shape: shape, bbox: shape.Bounds()}
+		idx.tree.insert(rtreeEntry{bbox: items[i].bbox, item: items[i]})
+	}
+	idx.alerts[alert.Identifier] = items
+	return nil
+}
+
+// Remove removes the alert with the identifier, it returns false when no
+// such alert was indexed
+func (idx *Index) Remove(identifier string) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.remove(identifier)
+}
+
+// RemoveExpired removes the alerts for which every info has expired by now,
+// it returns the identifiers of the removed alerts
+func (idx *Index) RemoveExpired(now time.Time) []string {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	var removed []string
+	for identifier, items := range idx.alerts {
+		if expired(items[0].alert, now) {
+			idx.remove(identifier)
+			removed = append(removed, identifier)
+		}
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+// Len returns the number of indexed alerts
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.alerts)
+}
+
+// Query returns the alerts with a shape containing the point, ordered by identifier
+func (idx *Index) Query(lat float64, lon float64) []*cap.Alert {
+	point := Point{Lat: lat, Lon: lon}
+	box := BBox{MinLat: lat, MinLon: lon, MaxLat: lat, MaxLon: lon}
+	return idx.search(box, func(item *indexItem) bool {
+		return item.shape.Contains(point)
+	})
+}
+
+// QueryBBox returns the alerts with a shape intersecting the box, ordered by identifier
+func (idx *Index) QueryBBox(box BBox) []*cap.Alert {
+	return idx.search(box, func(item *indexItem) bool {
+		return item.shape.Intersects(box)
+	})
+}
+
+func (idx *Index) search(box BBox, match func(*indexItem) bool) []*cap.Alert {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	found := make(map[string]*cap.Alert)
+	idx.tree.search(box, func(item *indexItem) {
+		if _, ok := found[item.alert.Identifier]; ok {
+			return
+		}
+		if match(item) {
+			found[item.alert.Identifier] = item.alert
+		}
+	})
+	identifiers := make([]string, 0, len(found))
+	for identifier := range found {
+		identifiers = append(identifiers, identifier)
+	}
+	sort.Strings(identifiers)
+	alerts := make([]*cap.Alert, len(identifiers))
+	for i, identifier := range identifiers {
+		alerts[i] = found[identifier]
+	}
+	return alerts
+}
+
+func (idx *Index) remove(identifier string) bool {
+	items, ok := idx.alerts[identifier]
+	if !ok {
+		return false
+	}
+	for _, item := range items {
+		idx.tree.remove(item)
+	}
+	delete(idx.alerts, identifier)
+	return true
+}
+
+// expired - whether every info of the alert has an expires time before now,
+// alerts without any expires time never expire
+func expired(alert *cap.Alert, now time.Time) bool {
+	if len(alert.Info) == 0 {
+		return false
+	}
+	for _, info := range alert.Info {
+		expires, err := cap.TimeParse(info.Expires)
+		if err != nil || !expires.Before(now) {
+			return false
+		}
+	}
+	return true
+}
+
+// R-tree node capacity, nodes are split when they exceed maxEntries and
+// dissolved when they fall below minEntries
+const (
+	maxEntries = 16
+	minEntries = 6
+)
+
+// rtree - an R-tree of the bounding boxes of indexItems
+type rtree struct {
+	root *rtreeNode
+}
+
+type rtreeNode struct {
+	leaf    bool
+	entries []rtreeEntry
+}
+
+// rtreeEntry - either a child node (in branch nodes) or an item (in leaf nodes)
+type rtreeEntry struct {
+	bbox  BBox
+	child *rtreeNode
+	item  *indexItem
+}
+
+func (t *rtree) insert(entry rtreeEntry) {
+	if t.root == nil {
+		t.root = &rtreeNode{leaf: true}
+	}
+	if split := t.root.insert(entry); split != nil {
+		old := t.root
+		t.root = &rtreeNode{entries: []rtreeEntry{
+			{bbox: old.bounds(), child: old},
+			{bbox: split.bounds(), child: split},
+		}}
+	}
+}
+
+func (t *rtree) remove(item *indexItem) {
+	if t.root == nil {
+		return
+	}
+	var orphans []rtreeEntry
+	if !t.root.remove(item, &orphans) {
+		return
+	}
+	for !t.root.leaf && len(t.root.entries) == 1 {
+		t.root = t.root.entries[0].child
+	}
+	if len(t.root.entries) == 0 {
+		t.root = nil
+	}
+	for _, orphan := range orphans {
+		t.insert(orphan)
+	}
+}
+
+func (t *rtree) search(box BBox, fn func(*indexItem)) {
+	if t.root != nil {
+		t.root.search(box, fn)
+	}
+}
+
+// insert adds the entry below n, returning a new sibling of n if n was split
+func (n *rtreeNode) insert(entry rtreeEntry) *rtreeNode {
+	if n.leaf {
+		n.entries = append(n.entries, entry)
+	} else {
+		i := n.chooseSubtree(entry.bbox)
+		child := n.entries[i].child
+		split := child.insert(entry)
+		n.entries[i].bbox = child.bounds()
+		if split != nil {
+			n.entries = append(n.entries, rtreeEntry{bbox: split.bounds(), child: split})
+		}
+	}
+	if len(n.entries) > maxEntries {
+		return n.split()
+	}
+	return nil
+}
+
+// chooseSubtree returns the entry needing the least enlargement to include box
+func (n *rtreeNode) chooseSubtree(box BBox) int {
+	best, bestEnlargement, bestArea := 0, 0.0, 0.0
+	for i, entry := range n.entries {
+		area := entry.bbox.area()
+		enlargement := entry.bbox.Union(box).area() - area
+		if i == 0 || enlargement < bestEnlargement || (enlargement == bestEnlargement && area < bestArea) {
+			best, bestEnlargement, bestArea = i, enlargement, area
+		}
+	}
+	return best
+}
+
+// split sorts the entries along the axis with the larger spread of centers and
+// moves the upper half into a new sibling node
+func (n *rtreeNode) split() *rtreeNode {
+	centers := emptyBBox()
+	for _, entry := range n.entries {
+		centers = centers.extend(entry.bbox.center())
+	}
+	byLat := centers.MaxLat-centers.MinLat > centers.MaxLon-centers.MinLon
+	sort.Slice(n.entries, func(i, j int) bool {
+		a, b := n.entries[i].bbox.center(), n.entries[j].bbox.center()
+		if byLat {
+			return a.Lat < b.Lat
+		}
+		return a.Lon < b.Lon
+	})
+	half := len(n.entries) / 2
+	sibling := &rtreeNode{leaf: n.leaf, entries: append([]rtreeEntry(nil), n.entries[half:]...)}
+	n.entries = append([]rtreeEntry(nil), n.entries[:half]...)
+	return sibling
+}
+
+// remove deletes the item below n, the leaf entries of dissolved nodes are
+// appended to orphans for reinsertion
+func (n *rtreeNode) remove(item *indexItem, orphans *[]rtreeEntry) bool {
+	if n.leaf {
+		for i, entry := range n.entries {
+			if entry.item == item {
+				n.entries = append(n.entries[:i], n.entries[i+1:]...)
+				return true
+			}
+		}
+		return false
+	}
+	for i := range n.entries {
+		entry := &n.entries[i]
+		if !entry.bbox.containsBBox(item.bbox) || !entry.child.remove(item, orphans) {
+			continue
+		}
+		if len(entry.child.entries) < minEntries {
+			entry.child.collect(orphans)
+			n.entries = append(n.entries[:i], n.entries[i+1:]...)
+		} else {
+			entry.bbox = entry.child.bounds()
+		}
+		return true
+	}
+	return false
+}
+
+func (n *rtreeNode) collect(entries *[]rtreeEntry) {
+	for _, entry := range n.entries {
+		if n.leaf {
+			*entries = append(*entries, entry)
+		} else {
+			entry.child.collect(entries)
+		}
+	}
+}
+
+func (n *rtreeNode) search(box BBox, fn func(*indexItem)) {
+	for _, entry := range n.entries {
+		if !entry.bbox.Intersects(box) {
+			continue
+		}
+		if n.leaf {
+			fn(entry.item)
+		} else {
+			entry.child.search(box, fn)
+		}
+	}
+}
+
+func (n *rtreeNode) bounds() BBox {
+	b := emptyBBox()
+	for _, entry := range n.entries {
+		b = b.Union(entry.bbox)
+	}
+	return b
+}
+
+func (b BBox) center() Point {
+	return Point{Lat: (b.MinLat + b.MaxLat) / 2, Lon: (b.MinLon + b.MaxLon) / 2}
+}
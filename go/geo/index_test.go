@@ -0,0 +1,136 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package geo
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/IBM/cap/go/cap"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSquareAlert(identifier string, lat float64, lon float64, size float64) *cap.Alert {
+	polygon := Polygon{{lat, lon}, {lat, lon + size}, {lat + size, lon + size}, {lat + size, lon}, {lat, lon}}
+	return &cap.Alert{
+		Identifier: identifier,
+		Info:       []cap.Info{{Area: []cap.Area{{Polygon: []string{polygon.String()}}}}},
+	}
+}
+
+func identifiers(alerts []*cap.Alert) []string {
+	found := []string{}
+	for _, alert := range alerts {
+		found = append(found, alert.Identifier)
+	}
+	return found
+}
+
+func TestIndexQueryReturnsContainingAlerts(t *testing.T) {
+	idx := NewIndex()
+	assert.Nil(t, idx.Insert(newSquareAlert("a", 30, -90, 2)))
+	assert.Nil(t, idx.Insert(newSquareAlert("b", 31, -89, 2)))
+	assert.Nil(t, idx.Insert(&cap.Alert{
+		Identifier: "c",
+		Info:       []cap.Info{{Area: []cap.Area{{Circle: []string{"40,-100 20"}}}}},
+	}))
+	assert.Equal(t, 3, idx.Len())
+	assert.Equal(t, []string{"a"}, identifiers(idx.Query(30.5, -89.5)))
+	assert.Equal(t, []string{"a", "b"}, identifiers(idx.Query(31.5, -88.5)))
+	assert.Equal(t, []string{"c"}, identifiers(idx.Query(40.1, -100)))
+	assert.Equal(t, []string{}, identifiers(idx.Query(0, 0)))
+	assert.Equal(t, []string{"a", "b"}, identifiers(idx.QueryBBox(BBox{MinLat: 29, MinLon: -91, MaxLat: 35, MaxLon: -85})))
+}
+
+func TestIndexInsertReplacesAndRemoveDeletes(t *testing.T) {
+	idx := NewIndex()
+	idx.Insert(newSquareAlert("a", 30, -90, 2))
+	idx.Insert(newSquareAlert("a", 40, -100, 2))
+	assert.Equal(t, 1, idx.Len())
+	assert.Equal(t, []string{}, identifiers(idx.Query(31, -89)))
+	assert.Equal(t, []string{"a"}, identifiers(idx.Query(41, -99)))
+	assert.True(t, idx.Remove("a"))
+	assert.False(t, idx.Remove("a"))
+	assert.Equal(t, 0, idx.Len())
+	assert.Equal(t, []string{}, identifiers(idx.Query(41, -99)))
+}
+
+func TestIndexInsertReturnsErrWithoutGeometry(t *testing.T) {
+	idx := NewIndex()
+	alert := cap.Alert{Identifier: "a", Info: []cap.Info{{Area: []cap.Area{{AreaDesc: "Nowhere", Polygon: []string{""}}}}}}
+	assert.Equal(t, ErrNoGeometry, idx.Insert(&alert))
+	alert.Info[0].Area[0].Polygon = []string{"1,2 3"}
+	assert.Error(t, idx.Insert(&alert))
+	assert.Equal(t, 0, idx.Len())
+}
+
+func TestIndexRemoveExpired(t *testing.T) {
+	now := time.Date(2018, 8, 15, 12, 0, 0, 0, time.UTC)
+	idx := NewIndex()
+	expiredAlert := newSquareAlert("expired", 30, -90, 2)
+	expiredAlert.Info[0].Expires = cap.Time(now.Add(-time.Hour))
+	activeAlert := newSquareAlert("active", 30, -90, 2)
+	activeAlert.Info[0].Expires = cap.Time(now.Add(time.Hour))
+	idx.Insert(expiredAlert)
+	idx.Insert(activeAlert)
+	idx.Insert(newSquareAlert("no-expiry", 30, -90, 2))
+	assert.Equal(t, []string{"expired"}, idx.RemoveExpired(now))
+	assert.Equal(t, []string{"active", "no-expiry"}, identifiers(idx.Query(31, -89)))
+}
+
+func TestIndexMatchesBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	idx := NewIndex()
+	alerts := make(map[string]*cap.Alert)
+	for i := 0; i < 2000; i++ {
+		id := fmt.Sprintf("alert-%04d", i)
+		alerts[id] = newSquareAlert(id, r.Float64()*50+20, r.Float64()*60-130, r.Float64()*3)
+		assert.Nil(t, idx.Insert(alerts[id]))
+	}
+	for i := 0; i < 2000; i += 3 {
+		id := fmt.Sprintf("alert-%04d", i)
+		assert.True(t, idx.Remove(id))
+		delete(alerts, id)
+	}
+	assert.Equal(t, len(alerts), idx.Len())
+	for i := 0; i < 200; i++ {
+		lat, lon := r.Float64()*50+20, r.Float64()*60-130
+		expected := []string{}
+		for id, alert := range alerts {
+			polygon, _ := ParsePolygon(alert.Info[0].Area[0].Polygon[0])
+			if polygon.Contains(Point{lat, lon}) {
+				expected = append(expected, id)
+			}
+		}
+		actual := identifiers(idx.Query(lat, lon))
+		assert.ElementsMatch(t, expected, actual)
+	}
+}
+
+func BenchmarkIndexQuery(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	idx := NewIndex()
+	for i := 0; i < 5000; i++ {
+		idx.Insert(newSquareAlert(fmt.Sprintf("alert-%04d", i), r.Float64()*50+20, r.Float64()*60-130, r.Float64()*3))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		idx.Query(r.Float64()*50+20, r.Float64()*60-130)
+	}
+}
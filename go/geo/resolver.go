@@ -0,0 +1,315 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package geo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/IBM/cap/go/atom"
+	"github.com/IBM/cap/go/cap"
+)
+
+// GeocodeSAME - the 6 digit Specific Area Message Encoding code used by the
+// NWS in CAP areas, 0 followed by the state and county FIPS codes as in the
+// atom.GeocodeFIPS6 codes of the Atom feed
+const GeocodeSAME string = "SAME"
+
+// Resolver - maps a geocode to the shapes of the area it identifies
+type Resolver interface {
+	// Resolve returns the shapes for the geocode value, or nil when it is unknown
+	Resolve(name string, value string) []Shape
+}
+
+// MultiPolygon - one or more polygons, each an outer ring followed by any holes
+type MultiPolygon [][]Polygon
+
+// Boundaries - a Resolver backed by boundary data, usually loaded from
+// GeoJSON files. Boundaries is safe for concurrent use.
+type Boundaries struct {
+	mu     sync.RWMutex
+	shapes map[string][]Shape
+}
+
+// KeyFunc - returns the geocodes identifying a boundary feature from its properties
+type KeyFunc func(properties map[string]interface{}) []cap.NamedValue
+
+// NewBoundaries creates an empty Boundaries
+func NewBoundaries() *Boundaries {
+	return &Boundaries{shapes: make(map[string][]Shape)}
+}
+
+// Add registers a shape for the geocode, shapes added for the same geocode accumulate
+func (b *Boundaries) Add(name string, value string, shape Shape) {
+	key := geocodeKey(name, value)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.shapes[key] = append(b.shapes[key], shape)
+}
+
+// Resolve returns the shapes registered for the geocode
+func (b *Boundaries) Resolve(name string, value string) []Shape {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.shapes[geocodeKey(name, value)]
+}
+
+// Len returns the number of geocodes with registered shapes
+func (b *Boundaries) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.shapes)
+}
+
+// LoadGeoJSON reads a GeoJSON FeatureCollection of Polygon and MultiPolygon
+// features, registering each feature under the geocodes returned by keys.
+// Features with other geometry types or without geocodes are skipped.
+func (b *Boundaries) LoadGeoJSON(r io.Reader, keys KeyFunc) error {
+	var collection struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Properties map[string]interface{} `json:"properties"`
+			Geometry   json.RawMessage        `json:"geometry"`
+		} `json:"features"`
+	}
+	if err := json.NewDecoder(r).Decode(&collection); err != nil {
+		return err
+	}
+	if collection.Type != "FeatureCollection" {
+		return fmt.Errorf("GeoJSON type %q is not a FeatureCollection", collection.Type)
+	}
+	for index, feature := range collection.Features {
+		geocodes := keys(feature.Properties)
+		if len(geocodes) == 0 || len(feature.Geometry) == 0 || string(feature.Geometry) == "null" {
+			continue
+		}
+		shape, err := ParseGeoJSONGeometry(feature.Geometry)
+		if err == errUnsupportedGeometry {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("feature %d: %v", index, err)
+		}
+		for _, geocode := range geocodes {
+			b.Add(geocode.ValueName, geocode.Value, shape)
+		}
+	}
+	return nil
+}
+
+// NWSCountyKeys maps the properties of the NWS county boundary file (STATE,
+// FIPS), or of Census county files (STATEFP, COUNTYFP), to SAME, FIPS6 and
+// county UGC geocodes
+func NWSCountyKeys(properties map[string]interface{}) []cap.NamedValue {
+	state := propertyString(properties, "STATE", 0)
+	fips := propertyString(properties, "FIPS", 5)
+	if fips == "" {
+		fips = propertyString(properties, "STATEFP", 2) + propertyString(properties, "COUNTYFP", 3)
+	}
+	if len(fips) != 5 {
+		return nil
+	}
+	geocodes := []cap.NamedValue{{ValueName: GeocodeSAME, Value: "0" + fips}}
+	if state != "" {
+		geocodes = append(geocodes, cap.NamedValue{ValueName: atom.GeocodeUGC, Value: state + "C" + fips[2:]})
+	}
+	return geocodes
+}
+
+// NWSZoneKeys maps the properties of the NWS forecast zone boundary files
+// (STATE, ZONE) to zone UGC geocodes
+func NWSZoneKeys(properties map[string]interface{}) []cap.NamedValue {
+	state := propertyString(properties, "STATE", 0)
+	zone := propertyString(properties, "ZONE", 3)
+	if state == "" || zone == "" {
+		return nil
+	}
+	return []cap.NamedValue{{ValueName: atom.GeocodeUGC, Value: state + "Z" + zone}}
+}
+
+// ResolveGeocodes returns the shapes of all of the geocode values, values may
+// be whitespace delimited lists as sent in the NWS Atom feed
+func ResolveGeocodes(r Resolver, name string, values []string) []Shape {
+	var shapes []Shape
+	for _, value := range values {
+		for _, code := range strings.Fields(value) {
+			shapes = append(shapes, r.Resolve(name, code)...)
+		}
+	}
+	return shapes
+}
+
+// ResolveAreaShapes returns the polygons and circles of the area, or when it
+// has none, the shapes of its UGC geocodes falling back to its SAME and FIPS6
+// geocodes
+func ResolveAreaShapes(area *cap.Area, r Resolver) ([]Shape, error) {
+	shapes, err := AreaShapes(area)
+	if err != nil || len(shapes) > 0 || r == nil {
+		return shapes, err
+	}
+	return resolveAny(r, area.GetGeocodes), nil
+}
+
+// ResolveAlertShapes returns the shapes of all of the areas of all of the
+// infos of the alert, resolving geocodes for areas without polygons or circles
+func ResolveAlertShapes(alert *cap.Alert, r Resolver) ([]Shape, error) {
+	var shapes []Shape
+	for i := range alert.Info {
+		for j := range alert.Info[i].Area {
+			found, err := ResolveAreaShapes(&alert.Info[i].Area[j], r)
+			if err != nil {
+				return nil, err
+			}
+			shapes = append(shapes, found...)
+		}
+	}
+	return shapes, nil
+}
+
+// ResolveEntryShapes returns the polygons and circles of an Atom feed entry,
+// or when it has none, the shapes of its geocodes
+func ResolveEntryShapes(entry *atom.Entry, r Resolver) ([]Shape, error) {
+	area := cap.Area{Polygon: entry.Polygon, Circle: entry.Circle}
+	shapes, err := AreaShapes(&area)
+	if err != nil || len(shapes) > 0 || r == nil {
+		return shapes, err
+	}
+	return resolveAny(r, entry.Geocode.GetGeocodes), nil
+}
+
+// resolveAny resolves UGC geocodes, which are the most precise, falling back
+// to SAME and FIPS6
+func resolveAny(r Resolver, geocodes func(name string) []string) []Shape {
+	if shapes := ResolveGeocodes(r, atom.GeocodeUGC, geocodes(atom.GeocodeUGC)); len(shapes) > 0 {
+		return shapes
+	}
+	if shapes := ResolveGeocodes(r, GeocodeSAME, geocodes(GeocodeSAME)); len(shapes) > 0 {
+		return shapes
+	}
+	return ResolveGeocodes(r, atom.GeocodeFIPS6, geocodes(atom.GeocodeFIPS6))
+}
+
+// geocodeKey normalizes geocodes so that equivalent codes share a key, FIPS6
+// and SAME codes are keyed on the state and county digits
+func geocodeKey(name string, value string) string {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if name == atom.GeocodeFIPS6 || name == GeocodeSAME {
+		if len(value) == 6 {
+			value = value[1:]
+		}
+		return "FIPS:" + value
+	}
+	return name + ":" + value
+}
+
+// propertyString returns the property as a string, numbers, such as FIPS
+// codes written without quotes, are zero padded to width digits
+func propertyString(properties map[string]interface{}, name string, width int) string {
+	switch value := properties[name].(type) {
+	case string:
+		return strings.TrimSpace(value)
+	case float64:
+		return fmt.Sprintf("%0*.0f", width, value)
+	}
+	return ""
+}
+
+// String - the outer rings in the CAP polygon format, one per line as they
+// would be written in separate polygon elements. CAP cannot express holes.
+func (m MultiPolygon) String() string {
+	var polygons []string
+	for _, rings := range m {
+		if len(rings) > 0 {
+			polygons = append(polygons, rings[0].String())
+		}
+	}
+	return strings.Join(polygons, "\n")
+}
+
+// Bounds - the smallest BBox enclosing the outer rings
+func (m MultiPolygon) Bounds() BBox {
+	b := emptyBBox()
+	for _, rings := range m {
+		if len(rings) > 0 {
+			b = b.Union(rings[0].Bounds())
+		}
+	}
+	return b
+}
+
+// Contains - whether the point lies inside an outer ring and outside its holes
+func (m MultiPolygon) Contains(point Point) bool {
+	for _, rings := range m {
+		if len(rings) == 0 || !rings[0].Contains(point) {
+			continue
+		}
+		inHole := false
+		for _, hole := range rings[1:] {
+			if hole.Contains(point) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+	return false
+}
+
+// Intersects - whether the multipolygon and the box overlap, boxes lying
+// entirely within a hole do not intersect
+func (m MultiPolygon) Intersects(b BBox) bool {
+	for _, rings := range m {
+		if len(rings) == 0 || !rings[0].Intersects(b) {
+			continue
+		}
+		inHole := false
+		for _, hole := range rings[1:] {
+			if containsBBox(hole, b) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+	return false
+}
+
+// containsBBox - whether the box lies entirely inside the polygon
+func containsBBox(p Polygon, b BBox) bool {
+	corners := b.corners()
+	for _, corner := range corners {
+		if !p.Contains(corner) {
+			return false
+		}
+	}
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		for k, l := 0, len(corners)-1; k < len(corners); l, k = k, k+1 {
+			if segmentsIntersect(p[j], p[i], corners[l], corners[k]) {
+				return false
+			}
+		}
+	}
+	return true
+}
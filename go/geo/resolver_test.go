@@ -0,0 +1,158 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package geo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/IBM/cap/go/atom"
+	"github.com/IBM/cap/go/cap"
+	"github.com/stretchr/testify/assert"
+)
+
+const countiesGeoJSON = `{
+  "type": "FeatureCollection",
+  "features": [
+    {
+      "type": "Feature",
+      "properties": {"STATE": "TX", "CWA": "HGX", "COUNTYNAME": "Harris", "FIPS": "48201"},
+      "geometry": {"type": "Polygon", "coordinates": [
+        [[-95.9, 29.5], [-94.9, 29.5], [-94.9, 30.2], [-95.9, 30.2], [-95.9, 29.5]],
+        [[-95.5, 29.8], [-95.3, 29.8], [-95.3, 29.9], [-95.5, 29.9], [-95.5, 29.8]]
+      ]}
+    },
+    {
+      "type": "Feature",
+      "properties": {"STATE": "AK", "CWA": "AFG", "COUNTYNAME": "North Slope", "FIPS": "02185"},
+      "geometry": {"type": "MultiPolygon", "coordinates": [
+        [[[-160, 68], [-141, 68], [-141, 71], [-160, 71], [-160, 68]]],
+        [[[-170, 65], [-168, 65], [-168, 66], [-170, 66], [-170, 65]]]
+      ]}
+    },
+    {
+      "type": "Feature",
+      "properties": {"STATE": "TX", "FIPS": "48999"},
+      "geometry": {"type": "Point", "coordinates": [-95, 30]}
+    }
+  ]
+}`
+
+const zonesGeoJSON = `{
+  "type": "FeatureCollection",
+  "features": [
+    {
+      "type": "Feature",
+      "properties": {"STATE": "AK", "ZONE": "204", "NAME": "Eastern Beaufort Sea Coast"},
+      "geometry": {"type": "Polygon", "coordinates": [[[-152, 69.5], [-141, 69.5], [-141, 70.5], [-152, 70.5], [-152, 69.5]]]}
+    }
+  ]
+}`
+
+func loadBoundaries(t *testing.T) *Boundaries {
+	boundaries := NewBoundaries()
+	if err := boundaries.LoadGeoJSON(strings.NewReader(countiesGeoJSON), NWSCountyKeys); err != nil {
+		t.Fatal(err)
+	}
+	if err := boundaries.LoadGeoJSON(strings.NewReader(zonesGeoJSON), NWSZoneKeys); err != nil {
+		t.Fatal(err)
+	}
+	return boundaries
+}
+
+func TestBoundariesResolveNWSGeocodes(t *testing.T) {
+	boundaries := loadBoundaries(t)
+	assert.Equal(t, 5, boundaries.Len())
+	assert.Equal(t, 1, len(boundaries.Resolve("SAME", "048201")))
+	assert.Equal(t, 1, len(boundaries.Resolve("FIPS6", "048201")))
+	assert.Equal(t, 1, len(boundaries.Resolve("UGC", "TXC201")))
+	assert.Equal(t, 1, len(boundaries.Resolve("UGC", "akz204")))
+	assert.Nil(t, boundaries.Resolve("UGC", "TXC999"))
+	assert.Nil(t, boundaries.Resolve("SAME", "048999"))
+}
+
+func TestNWSKeysZeroPadNumericProperties(t *testing.T) {
+	assert.Equal(t, []cap.NamedValue{{ValueName: "SAME", Value: "001001"}, {ValueName: "UGC", Value: "ALC001"}},
+		NWSCountyKeys(map[string]interface{}{"STATE": "AL", "STATEFP": float64(1), "COUNTYFP": float64(1)}))
+	assert.Equal(t, []cap.NamedValue{{ValueName: "SAME", Value: "001001"}},
+		NWSCountyKeys(map[string]interface{}{"FIPS": float64(1001)}))
+	assert.Equal(t, []cap.NamedValue{{ValueName: "UGC", Value: "AKZ004"}},
+		NWSZoneKeys(map[string]interface{}{"STATE": "AK", "ZONE": float64(4)}))
+
+	boundaries := NewBoundaries()
+	err := boundaries.LoadGeoJSON(strings.NewReader(`{"type": "FeatureCollection", "features": [
+		{"properties": {"STATE": "AL", "STATEFP": 1, "COUNTYFP": 1}, "geometry": {"type": "Polygon", "coordinates": [[[-87, 32], [-86, 32], [-86, 33], [-87, 32]]]}}
+	]}`), NWSCountyKeys)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(boundaries.Resolve("FIPS6", "001001")))
+	assert.Equal(t, 1, len(boundaries.Resolve("UGC", "ALC001")))
+}
+
+func TestMultiPolygonString(t *testing.T) {
+	square := Polygon{{Lat: 0, Lon: 0}, {Lat: 0, Lon: 2}, {Lat: 2, Lon: 2}, {Lat: 0, Lon: 0}}
+	hole := Polygon{{Lat: 0.5, Lon: 0.5}, {Lat: 0.5, Lon: 1}, {Lat: 1, Lon: 1}, {Lat: 0.5, Lon: 0.5}}
+	other := Polygon{{Lat: 5, Lon: 5}, {Lat: 5, Lon: 6}, {Lat: 6, Lon: 6}, {Lat: 5, Lon: 5}}
+	// holes cannot be written as CAP polygons and are left out
+	assert.Equal(t, "0,0 0,2 2,2 0,0\n5,5 5,6 6,6 5,5", MultiPolygon{{square, hole}, {other}}.String())
+}
+
+func TestMultiPolygonHonorsHoles(t *testing.T) {
+	harris := loadBoundaries(t).Resolve("UGC", "TXC201")[0]
+	assert.True(t, harris.Contains(Point{Lat: 30, Lon: -95}))
+	assert.False(t, harris.Contains(Point{Lat: 29.85, Lon: -95.4}))
+	assert.False(t, harris.Intersects(BBox{MinLat: 29.84, MinLon: -95.45, MaxLat: 29.86, MaxLon: -95.35}))
+	assert.True(t, harris.Intersects(BBox{MinLat: 29.84, MinLon: -95.45, MaxLat: 29.95, MaxLon: -95.35}))
+}
+
+func TestLoadGeoJSONReturnsErrForInvalidData(t *testing.T) {
+	boundaries := NewBoundaries()
+	assert.Error(t, boundaries.LoadGeoJSON(strings.NewReader(`{"type": "Feature"}`), NWSZoneKeys))
+	assert.Error(t, boundaries.LoadGeoJSON(strings.NewReader(`not json`), NWSZoneKeys))
+	err := boundaries.LoadGeoJSON(strings.NewReader(`{"type": "FeatureCollection", "features": [
+		{"properties": {"STATE": "TX", "ZONE": "213"}, "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 1], [0, 0]]]}}
+	]}`), NWSZoneKeys)
+	assert.Equal(t, "feature 0: linear ring has 3 positions, at least 4 are required", err.Error())
+}
+
+func TestIndexResolvesAreasWithoutPolygons(t *testing.T) {
+	idx := NewIndex()
+	idx.Resolver = loadBoundaries(t)
+	alert := cap.Alert{Identifier: "flood", Info: []cap.Info{{Area: []cap.Area{{AreaDesc: "Harris"}}}}}
+	alert.Info[0].Area[0].AddGeocode("SAME", "048201")
+	assert.Nil(t, idx.Insert(&alert))
+	assert.Equal(t, []string{"flood"}, identifiers(idx.Query(30, -95)))
+	assert.Equal(t, []string{}, identifiers(idx.Query(29.85, -95.4)))
+
+	unknown := cap.Alert{Identifier: "unknown", Info: []cap.Info{{Area: []cap.Area{{AreaDesc: "Nowhere"}}}}}
+	unknown.Info[0].Area[0].AddGeocode("UGC", "ZZZ999")
+	assert.Equal(t, ErrNoGeometry, idx.Insert(&unknown))
+}
+
+func TestResolveEntryShapesUsesFeedGeocodes(t *testing.T) {
+	entry := atom.Entry{Polygon: []string{""}, Geocode: atom.Geocode{Pairs: []atom.NamedValue{
+		{ValueName: "FIPS6", Value: "002185"},
+		{ValueName: "UGC", Value: "AKZ204"},
+	}}}
+	shapes, err := ResolveEntryShapes(&entry, loadBoundaries(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the UGC zone is preferred over the much larger county
+	assert.Equal(t, 1, len(shapes))
+	assert.True(t, shapes[0].Contains(Point{Lat: 70, Lon: -145}))
+	assert.False(t, shapes[0].Contains(Point{Lat: 68.5, Lon: -145}))
+}
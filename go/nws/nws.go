@@ -0,0 +1,366 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/IBM/cap/go/atom"
+	"github.com/IBM/cap/go/cap"
+	"github.com/IBM/cap/go/geo"
+)
+
+// APIURL is the base URL of the NWS API, which serves active alerts as GeoJSON
+// features with the CAP elements as properties
+const APIURL string = "https://api.weather.gov"
+
+// Accept headers sent when retrieving alert collections and single alerts
+const (
+	geoJSONAccept string = "application/geo+json"
+	capAccept     string = "application/cap+xml"
+)
+
+// maxPages - the most pages AllActiveAlerts follows
+const maxPages = 100
+
+// FeatureCollection - a page of alerts
+type FeatureCollection struct {
+	Type       string      `json:"type"`
+	Title      string      `json:"title"`
+	Updated    string      `json:"updated"`
+	Features   []Feature   `json:"features"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+// Pagination - the cursor of the next page of a collection
+type Pagination struct {
+	Next string `json:"next"`
+}
+
+// Feature - a single alert, geometry is null for alerts which only have geocodes
+type Feature struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Geometry   json.RawMessage `json:"geometry"`
+	Properties Properties      `json:"properties"`
+}
+
+// Properties - the CAP elements of an alert
+type Properties struct {
+	ID            string              `json:"id"`
+	AreaDesc      string              `json:"areaDesc"`
+	Geocode       map[string][]string `json:"geocode"`
+	AffectedZones []string            `json:"affectedZones"`
+	References    []Reference         `json:"references"`
+	Sent          string              `json:"sent"`
+	Effective     string              `json:"effective"`
+	Onset         string              `json:"onset"`
+	Expires       string              `json:"expires"`
+	Ends          string              `json:"ends"`
+	Status        string              `json:"status"`
+	MessageType   string              `json:"messageType"`
+	Category      string              `json:"category"`
+	Severity      string              `json:"severity"`
+	Certainty     string              `json:"certainty"`
+	Urgency       string              `json:"urgency"`
+	Event         string              `json:"event"`
+	Sender        string              `json:"sender"`
+	SenderName    string              `json:"senderName"`
+	Headline      string              `json:"headline"`
+	Description   string              `json:"description"`
+	Instruction   string              `json:"instruction"`
+	Response      string              `json:"response"`
+	Parameters    map[string][]string `json:"parameters"`
+	EventCode     map[string][]string `json:"eventCode"`
+}
+
+// Reference - an earlier alert referenced by an alert
+type Reference struct {
+	ID         string `json:"@id"`
+	Identifier string `json:"identifier"`
+	Sender     string `json:"sender"`
+	Sent       string `json:"sent"`
+}
+
+// Problem - an error response of the API, in the RFC 7807 problem details format
+type Problem struct {
+	Type          string `json:"type"`
+	Title         string `json:"title"`
+	Status        int    `json:"status"`
+	Detail        string `json:"detail"`
+	Instance      string `json:"instance"`
+	CorrelationID string `json:"correlationId"`
+}
+
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return fmt.Sprintf("HTTP status code: %d", p.Status)
+	}
+	return fmt.Sprintf("HTTP status code: %d: %s", p.Status, p.Detail)
+}
+
+// Query - the filters of the active alerts, alerts match every non empty field
+// and any of the values of a field
+type Query struct {
+	Area        []string   // Area - state and marine area codes, e.g. TX
+	Zone        []string   // Zone - zone and county identifiers, e.g. TXZ192 or TXC453
+	Region      []string   // Region - marine region codes, e.g. GM
+	Event       []string   // Event - event names, e.g. Flash Flood Warning
+	Status      []string   // Status - e.g. Actual
+	MessageType []string   // MessageType - e.g. Alert, Update or Cancel
+	Severity    []string   // Severity - e.g. Extreme or Severe
+	Urgency     []string   // Urgency - e.g. Immediate or Expected
+	Certainty   []string   // Certainty - e.g. Observed or Likely
+	Point       *geo.Point // Point - alerts with an area containing the point
+}
+
+// Values - the query parameters of the query
+func (q *Query) Values() url.Values {
+	values := url.Values{}
+	set := func(name string, list []string) {
+		if len(list) > 0 {
+			values.Set(name, strings.Join(list, ","))
+		}
+	}
+	set("area", q.Area)
+	set("zone", q.Zone)
+	set("region", q.Region)
+	set("event", q.Event)
+	set("status", q.Status)
+	set("message_type", q.MessageType)
+	set("severity", q.Severity)
+	set("urgency", q.Urgency)
+	set("certainty", q.Certainty)
+	if q.Point != nil {
+		values.Set("point", strconv.FormatFloat(q.Point.Lat, 'f', 4, 64)+","+strconv.FormatFloat(q.Point.Lon, 'f', 4, 64))
+	}
+	return values
+}
+
+// Client - retrieves alerts from the NWS API
+type Client struct {
+	// Client - makes the requests with its user agent, which the API requires,
+	// retries, circuit breaker and body size limit, atom.DefaultClient is used
+	// when nil
+	Client *atom.Client
+	// BaseURL - the API, APIURL is used when empty
+	BaseURL string
+}
+
+// NewClient creates a Client making requests with a client created by
+// atom.NewClient
+func NewClient() *Client {
+	return &Client{
+		Client:  atom.NewClient(),
+		BaseURL: APIURL,
+	}
+}
+
+// ActiveAlerts retrieves the first page of active alerts matching the query
+func (c *Client) ActiveAlerts(ctx context.Context, q Query) (*FeatureCollection, error) {
+	alertsURL := c.baseURL() + "/alerts/active"
+	if values := q.Values(); len(values) > 0 {
+		alertsURL += "?" + values.Encode()
+	}
+	return c.collection(ctx, alertsURL)
+}
+
+// NextPage retrieves the page following the collection, it returns nil when
+// the collection is the last page
+func (c *Client) NextPage(ctx context.Context, fc *FeatureCollection) (*FeatureCollection, error) {
+	if fc.Pagination == nil || fc.Pagination.Next == "" {
+		return nil, nil
+	}
+	return c.collection(ctx, fc.Pagination.Next)
+}
+
+// AllActiveAlerts retrieves every page of active alerts matching the query,
+// following the pagination cursors until a page is empty or has no next page
+func (c *Client) AllActiveAlerts(ctx context.Context, q Query) ([]Feature, error) {
+	page, err := c.ActiveAlerts(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	features := page.Features
+	seen := make(map[string]bool)
+	for pages := 1; page != nil && len(page.Features) > 0 && page.Pagination != nil; pages++ {
+		next := page.Pagination.Next
+		if next == "" || seen[next] {
+			break
+		}
+		if pages == maxPages {
+			return nil, fmt.Errorf("more than %d pages of alerts", maxPages)
+		}
+		seen[next] = true
+		if page, err = c.NextPage(ctx, page); err != nil {
+			return nil, err
+		}
+		features = append(features, page.Features...)
+	}
+	return features, nil
+}
+
+// Alert retrieves the CAP alert with the identifier, as published by the API
+// in the CAP v1.2 format
+func (c *Client) Alert(ctx context.Context, id string) (*cap.Alert, []byte, error) {
+	body, err := c.get(ctx, c.baseURL()+"/alerts/"+url.PathEscape(id), capAccept)
+	if err != nil {
+		return nil, nil, err
+	}
+	alert, err := cap.ParseAlert(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return alert, body, nil
+}
+
+func (c *Client) collection(ctx context.Context, collectionURL string) (*FeatureCollection, error) {
+	body, err := c.get(ctx, collectionURL, geoJSONAccept)
+	if err != nil {
+		return nil, err
+	}
+	var fc FeatureCollection
+	if err := json.Unmarshal(body, &fc); err != nil {
+		return nil, err
+	}
+	return &fc, nil
+}
+
+// get retrieves rawURL, an error response is returned as a *Problem
+func (c *Client) get(ctx context.Context, rawURL string, accept string) ([]byte, error) {
+	body, _, err := c.client().Get(ctx, rawURL, accept)
+	if httpErr, ok := err.(*atom.HTTPError); ok {
+		problem := Problem{}
+		// the API describes errors as application/problem+json
+		json.Unmarshal(httpErr.Body, &problem)
+		problem.Status = httpErr.StatusCode
+		return nil, &problem
+	}
+	return body, err
+}
+
+func (c *Client) baseURL() string {
+	if c.BaseURL == "" {
+		return APIURL
+	}
+	return strings.TrimSuffix(c.BaseURL, "/")
+}
+
+func (c *Client) client() *atom.Client {
+	if c.Client == nil {
+		return atom.DefaultClient
+	}
+	return c.Client
+}
+
+// Alert converts the feature to a CAP alert with a single info and area. The
+// area's polygons are taken from the feature's geometry, features without a
+// geometry only have geocodes.
+func (f *Feature) Alert() (*cap.Alert, error) {
+	p := &f.Properties
+	alert := &cap.Alert{
+		Identifier: p.ID,
+		Sender:     p.Sender,
+		Sent:       cap.TimeStr(p.Sent),
+		Status:     p.Status,
+		MsgType:    p.MessageType,
+		Scope:      "Public",
+	}
+	var references []string
+	for _, reference := range p.References {
+		references = append(references, reference.Sender+","+reference.Identifier+","+reference.Sent)
+	}
+	if len(references) > 0 {
+		alert.References = []string{strings.Join(references, " ")}
+	}
+
+	info := cap.Info{
+		Language:    "en-US",
+		Event:       p.Event,
+		Urgency:     p.Urgency,
+		Severity:    p.Severity,
+		Certainty:   p.Certainty,
+		EventCode:   namedValues(p.EventCode),
+		Effective:   cap.TimeStr(p.Effective),
+		Onset:       cap.TimeStr(p.Onset),
+		Expires:     cap.TimeStr(p.Expires),
+		SenderName:  p.SenderName,
+		Headline:    p.Headline,
+		Description: p.Description,
+		Instruction: p.Instruction,
+		Web:         f.ID,
+		Parameter:   namedValues(p.Parameters),
+	}
+	if p.Category != "" {
+		info.Category = []string{p.Category}
+	}
+	if p.Response != "" {
+		info.ResponseType = []string{p.Response}
+	}
+
+	area := cap.Area{AreaDesc: p.AreaDesc, Geocode: namedValues(p.Geocode)}
+	if len(f.Geometry) > 0 && string(f.Geometry) != "null" {
+		multi, err := geo.ParseGeoJSONGeometry(f.Geometry)
+		if err != nil {
+			return nil, fmt.Errorf("alert %s: %v", p.ID, err)
+		}
+		for _, rings := range multi {
+			// CAP polygons have no holes, only the outer ring is kept
+			if len(rings) > 0 {
+				area.Polygon = append(area.Polygon, rings[0].String())
+			}
+		}
+	}
+	info.Area = []cap.Area{area}
+	alert.Info = []cap.Info{info}
+	return alert, nil
+}
+
+// Alerts converts the features of the collection to CAP alerts
+func (fc *FeatureCollection) Alerts() ([]*cap.Alert, error) {
+	alerts := make([]*cap.Alert, 0, len(fc.Features))
+	for i := range fc.Features {
+		alert, err := fc.Features[i].Alert()
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+// namedValues converts a map of values to named values, ordered by name
+func namedValues(m map[string][]string) []cap.NamedValue {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var values []cap.NamedValue
+	for _, name := range names {
+		for _, value := range m[name] {
+			values = append(values, cap.NamedValue{ValueName: name, Value: value})
+		}
+	}
+	return values
+}
@@ -0,0 +1,225 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nws
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/IBM/cap/go/atom"
+	"github.com/IBM/cap/go/cap"
+	"github.com/IBM/cap/go/geo"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestServer serves the example active alerts, with the pagination cursor
+// pointing at the server, an empty last page and the amber alert example as
+// a single CAP alert. The queries received are appended to queries.
+func newTestServer(t *testing.T, queries *[]url.Values) *httptest.Server {
+	active, err := ioutil.ReadFile("../../resources/nws_api_alerts_active_example.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alert, err := ioutil.ReadFile("../../resources/cap_amber_alert_example.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	active = bytes.Replace(active, []byte(`"next": "`+APIURL), []byte(`"next": "`+server.URL), 1)
+	mux.HandleFunc("/alerts/active", func(w http.ResponseWriter, r *http.Request) {
+		*queries = append(*queries, r.URL.Query())
+		if r.Header.Get("User-Agent") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"title": "Forbidden", "status": 403, "detail": "a User-Agent is required"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/geo+json")
+		w.Write(active)
+	})
+	mux.HandleFunc("/alerts", func(w http.ResponseWriter, r *http.Request) {
+		*queries = append(*queries, r.URL.Query())
+		w.Header().Set("Content-Type", "application/geo+json")
+		w.Write([]byte(`{"type": "FeatureCollection", "features": [], "pagination": {"next": "` + server.URL + `/alerts?cursor=end"}}`))
+	})
+	mux.HandleFunc("/alerts/KAR0-0306112239-SW", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/cap+xml")
+		w.Write(alert)
+	})
+	return server
+}
+
+func TestQueryValues(t *testing.T) {
+	q := Query{
+		Area:     []string{"TX", "OK"},
+		Event:    []string{"Flash Flood Warning"},
+		Severity: []string{"Severe", "Extreme"},
+		Urgency:  []string{"Immediate"},
+		Point:    &geo.Point{Lat: 30.2672, Lon: -97.7431},
+	}
+	assert.Equal(t, "area=TX%2COK&event=Flash+Flood+Warning&point=30.2672%2C-97.7431&severity=Severe%2CExtreme&urgency=Immediate", q.Values().Encode())
+	assert.Equal(t, "", (&Query{}).Values().Encode())
+}
+
+func TestClientActiveAlerts(t *testing.T) {
+	var queries []url.Values
+	server := newTestServer(t, &queries)
+	defer server.Close()
+	client := NewClient()
+	client.BaseURL = server.URL
+
+	fc, err := client.ActiveAlerts(context.Background(), Query{Zone: []string{"TXC453"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "TXC453", queries[0].Get("zone"))
+	assert.Equal(t, 2, len(fc.Features))
+	assert.Equal(t, "Flash Flood Warning", fc.Features[0].Properties.Event)
+	assert.Equal(t, []string{"TXC453", "TXC491"}, fc.Features[0].Properties.Geocode["UGC"])
+	assert.Equal(t, server.URL+"/alerts?cursor=eyJ0IjoxNTM0MzczODIwfQ", fc.Pagination.Next)
+
+	next, err := client.NextPage(context.Background(), fc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "eyJ0IjoxNTM0MzczODIwfQ", queries[1].Get("cursor"))
+	assert.Equal(t, 0, len(next.Features))
+
+	last, err := client.NextPage(context.Background(), &FeatureCollection{})
+	assert.Nil(t, last)
+	assert.Nil(t, err)
+}
+
+func TestClientAllActiveAlertsFollowsPages(t *testing.T) {
+	var queries []url.Values
+	server := newTestServer(t, &queries)
+	defer server.Close()
+	client := &Client{BaseURL: server.URL}
+	features, err := client.AllActiveAlerts(context.Background(), Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(features))
+	// the empty second page ends the iteration
+	assert.Equal(t, 2, len(queries))
+}
+
+func TestClientReturnsProblem(t *testing.T) {
+	var queries []url.Values
+	server := newTestServer(t, &queries)
+	defer server.Close()
+	// a transport which drops the User-Agent
+	transport := roundTripper(func(r *http.Request) (*http.Response, error) {
+		r.Header.Set("User-Agent", "")
+		return http.DefaultTransport.RoundTrip(r)
+	})
+	client := &Client{BaseURL: server.URL, Client: &atom.Client{HTTPClient: &http.Client{Transport: transport}}}
+	_, err := client.ActiveAlerts(context.Background(), Query{})
+	problem, ok := err.(*Problem)
+	if !ok {
+		t.Fatalf("expected *Problem, got %v", err)
+	}
+	assert.Equal(t, http.StatusForbidden, problem.Status)
+	assert.Equal(t, "HTTP status code: 403: a User-Agent is required", err.Error())
+}
+
+func TestClientRetriesTransientFailures(t *testing.T) {
+	var queries []url.Values
+	server := newTestServer(t, &queries)
+	defer server.Close()
+	failures := 1
+	handler := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures > 0 {
+			failures--
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+	retry := &atom.RetryPolicy{MaxRetries: 1, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	client := &Client{BaseURL: server.URL, Client: &atom.Client{Retry: retry}}
+	fc, err := client.ActiveAlerts(context.Background(), Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(fc.Features))
+}
+
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestClientAlert(t *testing.T) {
+	var queries []url.Values
+	server := newTestServer(t, &queries)
+	defer server.Close()
+	alert, raw, err := (&Client{BaseURL: server.URL}).Alert(context.Background(), "KAR0-0306112239-SW")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, 0, len(raw))
+	assert.Equal(t, "KAR0-0306112239-SW", alert.Identifier)
+}
+
+func TestFeatureAlert(t *testing.T) {
+	var queries []url.Values
+	server := newTestServer(t, &queries)
+	defer server.Close()
+	fc, err := (&Client{BaseURL: server.URL}).ActiveAlerts(context.Background(), Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alerts, err := fc.Alerts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(alerts))
+
+	alert := alerts[0]
+	assert.Equal(t, "urn:oid:2.49.0.1.840.0.4b5c8a5b1e4a0c3f0e2fd2f4d2b7c9e3a1d0e6f1.001.1", alert.Identifier)
+	assert.Equal(t, cap.TimeStr("2018-08-15T14:02:00-05:00"), alert.Sent)
+	assert.Equal(t, "Update", alert.MsgType)
+	assert.Equal(t, []string{"w-nws.webmaster@noaa.gov,urn:oid:2.49.0.1.840.0.1e4a0c3f0e2fd2f4d2b7c9e3a1d0e6f14b5c8a5b.001.1,2018-08-15T13:10:00-05:00"}, alert.References)
+	info := alert.Info[0]
+	assert.Equal(t, []string{"Met"}, info.Category)
+	assert.Equal(t, []string{"Avoid"}, info.ResponseType)
+	assert.Equal(t, "Flash Flood Warning", info.Event)
+	assert.Equal(t, "/O.EXT.KEWX.FF.W.0021.000000T0000Z-180815T2215Z/", info.GetParameter("VTEC"))
+	area := info.Area[0]
+	assert.Equal(t, []string{"048453", "048491"}, area.GetGeocodes("SAME"))
+	assert.Equal(t, []string{"30.36,-97.92 30.44,-97.66 30.21,-97.58 30.13,-97.86 30.36,-97.92"}, area.Polygon)
+
+	// the polygon is usable as geometry, e.g. by geo.Index
+	shapes, err := geo.AlertShapes(alert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, shapes[0].Contains(geo.Point{Lat: 30.2672, Lon: -97.7431}))
+
+	// the second alert has no geometry
+	assert.Equal(t, 0, len(alerts[1].Info[0].Area[0].Polygon))
+	assert.Equal(t, cap.TimeStr(""), alerts[1].Info[0].Onset)
+	assert.Nil(t, alerts[1].References)
+}
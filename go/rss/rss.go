@@ -0,0 +1,236 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rss
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/IBM/cap/go/shared"
+)
+
+// CAPMediaType is the media type of enclosures and links to CAP alerts
+const CAPMediaType string = "application/cap+xml"
+
+// atomNamespace - the namespace of atom:link elements in channels
+const atomNamespace string = "http://www.w3.org/2005/Atom"
+
+// xmlMediaTypes - generic media types of enclosed XML documents, which CAP
+// indexes use for CAP alerts when they do not use CAPMediaType
+var xmlMediaTypes = []string{"application/xml", "text/xml"}
+
+// timeFormats - the RFC 822 date formats found in RSS feeds, with and without
+// the day of week, with two and four digit years and with numeric and named
+// zones
+var timeFormats = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 06 15:04 -0700",
+	"Mon, 2 Jan 06 15:04 MST",
+	time.RFC822Z,
+	time.RFC822,
+}
+
+// RSS - root structure of an RSS 2.0 document
+type RSS struct {
+	XMLName xml.Name `xml:"rss"`
+	Version string   `xml:"version,attr"`
+	Channel Channel  `xml:"channel"`
+}
+
+// Channel - the feed, its metadata and items
+type Channel struct {
+	Title          string      `xml:"title"`                    // the name of the channel
+	Link           string      `xml:"link"`                     // the URL of the website corresponding to the channel
+	Description    string      `xml:"description"`              // describes the channel
+	Language       string      `xml:"language,omitempty"`       // the language the channel is written in
+	Copyright      string      `xml:"copyright,omitempty"`      // copyright notice for content in the channel
+	ManagingEditor string      `xml:"managingEditor,omitempty"` // email address for the person responsible for editorial content
+	PubDate        string      `xml:"pubDate,omitempty"`        // the publication date of the content in the channel
+	LastBuildDate  string      `xml:"lastBuildDate,omitempty"`  // the last time the content of the channel changed
+	Category       []Category  `xml:"category,omitempty"`       // the categories the channel belongs to
+	Generator      string      `xml:"generator,omitempty"`      // the program used to generate the channel
+	TTL            int         `xml:"ttl,omitempty"`            // minutes the channel may be cached before refreshing
+	AtomLink       []AtomLink  `xml:"http://www.w3.org/2005/Atom link,omitempty"`
+	Items          []Item      `xml:"item,omitempty"`
+	Extension      []Extension `xml:",any,omitempty"` // Custom extensions
+}
+
+// UnmarshalXML decodes the channel, separating the RSS link from atom:link
+// elements which encoding/xml would otherwise match to either field
+func (c *Channel) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type channel Channel // without the UnmarshalXML method
+	var x struct {
+		channel
+		Links []struct {
+			XMLName xml.Name
+			AtomLink
+			Value string `xml:",chardata"`
+		} `xml:"link"`
+	}
+	if err := d.DecodeElement(&x, &start); err != nil {
+		return err
+	}
+	*c = Channel(x.channel)
+	for _, link := range x.Links {
+		if link.XMLName.Space == atomNamespace {
+			c.AtomLink = append(c.AtomLink, link.AtomLink)
+		} else if c.Link == "" {
+			c.Link = strings.TrimSpace(link.Value)
+		}
+	}
+	return nil
+}
+
+// AtomLink - an atom:link in a channel, commonly the channel's self link
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+// Item - a single entry of the channel, at least one of title or description
+// must be present
+type Item struct {
+	Title       string      `xml:"title,omitempty"`       // the title of the item
+	Link        string      `xml:"link,omitempty"`        // the URL of the item
+	Description string      `xml:"description,omitempty"` // the item synopsis
+	Author      string      `xml:"author,omitempty"`      // email address of the author of the item
+	Category    []Category  `xml:"category,omitempty"`    // the categories the item belongs to
+	Comments    string      `xml:"comments,omitempty"`    // URL of a page for comments relating to the item
+	Enclosure   []Enclosure `xml:"enclosure,omitempty"`   // media objects attached to the item
+	GUID        *GUID       `xml:"guid,omitempty"`        // a string that uniquely identifies the item
+	PubDate     string      `xml:"pubDate,omitempty"`     // when the item was published
+	Source      *Source     `xml:"source,omitempty"`      // the channel the item came from
+	Extension   []Extension `xml:",any,omitempty"`        // Custom extensions
+}
+
+// Category - a category with an optional domain identifying the taxonomy
+type Category struct {
+	Value  string `xml:",chardata"`
+	Domain string `xml:"domain,attr,omitempty"`
+}
+
+// Enclosure - a media object attached to an item
+type Enclosure struct {
+	URL    string `xml:"url,attr"`
+	Length string `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// GUID - the unique identifier of an item, when IsPermaLink is not "false"
+// the identifier is also the URL of the item
+type GUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink string `xml:"isPermaLink,attr,omitempty"`
+}
+
+// Source - the channel an item was copied from
+type Source struct {
+	Value string `xml:",chardata"`
+	URL   string `xml:"url,attr"`
+}
+
+// Extension - used for adding custom content to an element
+type Extension = shared.Extension
+
+// ErrNotRSS is returned by Parse for documents which are not RSS 2.0
+var ErrNotRSS = errors.New("not an RSS 2.0 document")
+
+// Parse parses an RSS 2.0 document
+func Parse(data []byte) (*RSS, error) {
+	var feed RSS
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		if _, ok := err.(xml.UnmarshalError); ok {
+			return nil, ErrNotRSS
+		}
+		return nil, err
+	}
+	if feed.Version != "" && !strings.HasPrefix(feed.Version, "2.") {
+		return nil, ErrNotRSS
+	}
+	return &feed, nil
+}
+
+// ParseTime parses an RSS (RFC 822) date
+func ParseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	var err error
+	for _, format := range timeFormats {
+		var t time.Time
+		if t, err = time.Parse(format, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// PermaLink - the URL of the GUID, or "" when the GUID is not a permalink
+func (g *GUID) PermaLink() string {
+	if g == nil || strings.EqualFold(g.IsPermaLink, "false") {
+		return ""
+	}
+	return strings.TrimSpace(g.Value)
+}
+
+// ID - a unique identifier of the item, its GUID or else its link
+func (item *Item) ID() string {
+	if item.GUID != nil && strings.TrimSpace(item.GUID.Value) != "" {
+		return strings.TrimSpace(item.GUID.Value)
+	}
+	return strings.TrimSpace(item.Link)
+}
+
+// AlertURL - the URL of the item's CAP alert: the first enclosure with the CAP
+// media type, or else the first XML enclosure, or else a permalink GUID or the
+// link when it refers to an XML document
+func (item *Item) AlertURL() string {
+	if enclosure := item.enclosure(CAPMediaType); enclosure != nil {
+		return enclosure.URL
+	}
+	if enclosure := item.enclosure(xmlMediaTypes...); enclosure != nil {
+		return enclosure.URL
+	}
+	for _, candidate := range []string{item.GUID.PermaLink(), strings.TrimSpace(item.Link)} {
+		if strings.HasSuffix(strings.ToLower(candidate), ".xml") {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// enclosure returns the first enclosure with one of the media types
+func (item *Item) enclosure(mediaTypes ...string) *Enclosure {
+	for i := range item.Enclosure {
+		mediaType := strings.ToLower(strings.TrimSpace(item.Enclosure[i].Type))
+		if index := strings.Index(mediaType, ";"); index >= 0 {
+			mediaType = strings.TrimSpace(mediaType[:index])
+		}
+		for _, match := range mediaTypes {
+			if mediaType == match {
+				return &item.Enclosure[i]
+			}
+		}
+	}
+	return nil
+}
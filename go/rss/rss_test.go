@@ -0,0 +1,95 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rss
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getRSSExample(t *testing.T) *RSS {
+	xmlData, err := ioutil.ReadFile("../../resources/rss_cap_feed_example.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := Parse(xmlData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return feed
+}
+
+func TestParseRSS(t *testing.T) {
+	feed := getRSSExample(t)
+	channel := feed.Channel
+	assert.Equal(t, "2.0", feed.Version)
+	assert.Equal(t, "Example Meteorological Service CAP Alerts", channel.Title)
+	assert.Equal(t, "https://alerts.example.org/rss.xml", channel.AtomLink[0].Href)
+	assert.Equal(t, "self", channel.AtomLink[0].Rel)
+	assert.Equal(t, 3, len(channel.Items))
+
+	item := channel.Items[0]
+	assert.Equal(t, "urn:oid:2.49.0.0.999.0.2018.8.15.22.42.0", item.ID())
+	assert.Equal(t, "", item.GUID.PermaLink())
+	assert.Equal(t, "Met", item.Category[0].Value)
+	assert.Equal(t, "3514", item.Enclosure[0].Length)
+	assert.Equal(t, 2, len(channel.Items[1].Category))
+}
+
+func TestItemAlertURL(t *testing.T) {
+	items := getRSSExample(t).Channel.Items
+	// a CAP enclosure
+	assert.Equal(t, "https://alerts.example.org/cap/2018-0815-0042.xml", items[0].AlertURL())
+	// the link, which is also the permalink
+	assert.Equal(t, "https://alerts.example.org/cap/2018-0815-0039.xml", items[1].AlertURL())
+	assert.Equal(t, "https://alerts.example.org/cap/2018-0815-0039.xml", items[1].GUID.PermaLink())
+	// an XML enclosure after an image
+	assert.Equal(t, "https://alerts.example.org/cap/2018-0815-0031.xml", items[2].AlertURL())
+	assert.Equal(t, "https://alerts.example.org/warnings/2018-0815-0031.html", items[2].ID())
+
+	item := Item{Link: "https://alerts.example.org/warnings/1.html"}
+	assert.Equal(t, "", item.AlertURL())
+	item.Enclosure = []Enclosure{{URL: "https://alerts.example.org/cap/1", Type: "application/cap+xml; charset=utf-8"}}
+	assert.Equal(t, "https://alerts.example.org/cap/1", item.AlertURL())
+}
+
+func TestParseTime(t *testing.T) {
+	expected := time.Date(2018, 8, 15, 22, 57, 0, 0, time.UTC)
+	for _, s := range []string{
+		"Wed, 15 Aug 2018 22:57:00 +0000",
+		"Wed, 15 Aug 2018 22:57:00 GMT",
+		" 15 Aug 2018 17:57:00 -0500",
+		"Wed, 15 Aug 18 22:57 +0000",
+	} {
+		parsed, err := ParseTime(s)
+		if assert.Nil(t, err, s) {
+			assert.True(t, expected.Equal(parsed), "%s parsed as %v", s, parsed)
+		}
+	}
+	_, err := ParseTime("2018-08-15T22:57:00Z")
+	assert.Error(t, err)
+}
+
+func TestParseReturnsErrNotRSS(t *testing.T) {
+	_, err := Parse([]byte(`<feed xmlns="http://www.w3.org/2005/Atom"></feed>`))
+	assert.Equal(t, ErrNotRSS, err)
+	_, err = Parse([]byte(`<rss version="0.91"><channel></channel></rss>`))
+	assert.Equal(t, ErrNotRSS, err)
+}
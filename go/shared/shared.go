@@ -17,6 +17,11 @@ limitations under the License.
 package shared
 
 import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
 	"time"
 )
 
@@ -60,3 +65,389 @@ func Time(t time.Time) TimeStr {
 func TimeParse(t TimeStr) (time.Time, error) {
 	return time.Parse(time.RFC3339, string(t))
 }
+
+// Extension - used for adding custom content to an element. The XML of an
+// unmarshaled extension is self-contained, namespace prefixes declared on
+// ancestor elements are replaced by declarations on the elements using them.
+type Extension struct {
+	XMLName xml.Name
+	Attrs   Attrs  `xml:",any,attr"`
+	XML     string `xml:",innerxml"`
+	// Raw - the element as it was read by DecodeElement, with declarations of
+	// the namespaces it inherited added to its start tag. It is written back
+	// unchanged instead of XMLName, Attrs and XML, clear it after changing them.
+	Raw string `xml:"-"`
+	// Position - one more than the number of known sibling elements before
+	// the extension, zero writes it after them
+	Position int `xml:"-"`
+}
+
+// UnmarshalXML captures the extension element and re-encodes its content
+func (x *Extension) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	x.XMLName = start.Name
+	x.Attrs = withoutNamespaceDecls(start.Attr)
+
+	var buf bytes.Buffer
+	e := xml.NewEncoder(&buf)
+	depth := 0
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			t.Attr = withoutNamespaceDecls(t.Attr)
+			token = t
+		case xml.EndElement:
+			if depth == 0 {
+				if err := e.Flush(); err != nil {
+					return err
+				}
+				x.XML = buf.String()
+				return nil
+			}
+			depth--
+		}
+		if err := e.EncodeToken(xml.CopyToken(token)); err != nil {
+			return err
+		}
+	}
+}
+
+// MarshalXML writes the extension element with its attributes and content, or
+// its Raw XML
+func (x Extension) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if x.Raw != "" {
+		raw, inner, err := splitRaw(x.Raw)
+		if err != nil {
+			return err
+		}
+		return encodeRaw(e, raw, inner)
+	}
+	start.Name = x.XMLName
+	start.Attr = x.Attrs
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	d := xml.NewDecoder(strings.NewReader(x.XML))
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if t, ok := token.(xml.StartElement); ok {
+			t.Attr = withoutNamespaceDecls(t.Attr)
+			token = t
+		}
+		if err := e.EncodeToken(token); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// Attrs - unrecognized attributes of an element. Namespace declarations are
+// not kept, they are regenerated from the attribute names when marshaling.
+type Attrs []xml.Attr
+
+// UnmarshalXMLAttr appends attr unless it is a namespace declaration
+func (a *Attrs) UnmarshalXMLAttr(attr xml.Attr) error {
+	if !isNamespaceDecl(attr) {
+		*a = append(*a, attr)
+	}
+	return nil
+}
+
+func isNamespaceDecl(attr xml.Attr) bool {
+	return attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns")
+}
+
+func withoutNamespaceDecls(attrs []xml.Attr) Attrs {
+	var found Attrs
+	for _, attr := range attrs {
+		found.UnmarshalXMLAttr(attr)
+	}
+	return found
+}
+
+// DecodeElement decodes the element start into v, a pointer to a struct
+// whose innerxml field is inner, and records the Raw XML and Position of the
+// extensions of its Extension field
+func DecodeElement(d *xml.Decoder, start *xml.StartElement, v interface{}, inner *string, extension *[]Extension) error {
+	if err := d.DecodeElement(v, start); err != nil {
+		return err
+	}
+	extensions := *extension
+	children, err := childElements(*inner)
+	if err != nil {
+		return err
+	}
+	// the known elements are matched by local name whatever their namespace,
+	// so an element of the name of the next extension is that extension
+	known, next := 0, 0
+	for _, child := range children {
+		if next == len(extensions) || child.name.Local != extensions[next].XMLName.Local {
+			known++
+			continue
+		}
+		x := &extensions[next]
+		if x.Raw, err = declareInherited((*inner)[child.start:child.end], x, start.Name.Space); err != nil {
+			return err
+		}
+		x.Position = known + 1
+		next++
+	}
+	return nil
+}
+
+// EncodeElement writes v, the fields of an element without its extensions,
+// as the element start with the extensions among its child elements at their
+// Position, or after them. v must not implement xml.Marshaler.
+func EncodeElement(e *xml.Encoder, start xml.StartElement, v interface{}, extensions []Extension) error {
+	if len(extensions) == 0 {
+		return e.EncodeElement(v, start)
+	}
+	var buf bytes.Buffer
+	if err := xml.NewEncoder(&buf).EncodeElement(v, start); err != nil {
+		return err
+	}
+	element, inner, err := splitRaw(buf.String())
+	if err != nil {
+		return err
+	}
+	children, err := childElements(inner)
+	if err != nil {
+		return err
+	}
+	var content bytes.Buffer
+	next, written := 0, 0
+	for i, child := range children {
+		content.WriteString(inner[written:child.start])
+		written = child.start
+		for ; next < len(extensions) && extensions[next].Position > 0 && extensions[next].Position <= i+1; next++ {
+			if err := writeExtension(&content, &extensions[next]); err != nil {
+				return err
+			}
+		}
+	}
+	content.WriteString(inner[written:])
+	for ; next < len(extensions); next++ {
+		if err := writeExtension(&content, &extensions[next]); err != nil {
+			return err
+		}
+	}
+	return encodeRaw(e, element, content.String())
+}
+
+func writeExtension(w *bytes.Buffer, x *Extension) error {
+	if x.Raw != "" {
+		w.WriteString(x.Raw)
+		return nil
+	}
+	return xml.NewEncoder(w).Encode(x)
+}
+
+// rawElement - an element written with the names of its start tag and its
+// content as they were read
+type rawElement struct {
+	Attrs []xml.Attr `xml:",any,attr"`
+	Inner string     `xml:",innerxml"`
+}
+
+func encodeRaw(e *xml.Encoder, start xml.StartElement, inner string) error {
+	return e.EncodeElement(rawElement{Attrs: start.Attr, Inner: inner}, xml.StartElement{Name: start.Name})
+}
+
+// qualified returns the name as read by RawToken with its prefix in Local
+func qualified(name xml.Name) xml.Name {
+	if name.Space == "" {
+		return name
+	}
+	return xml.Name{Local: name.Space + ":" + name.Local}
+}
+
+// splitRaw returns the start tag of the element raw, with qualified names,
+// and its content
+func splitRaw(raw string) (xml.StartElement, string, error) {
+	d := xml.NewDecoder(strings.NewReader(raw))
+	for {
+		token, err := d.RawToken()
+		if err != nil {
+			return xml.StartElement{}, "", err
+		}
+		t, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		start := xml.StartElement{Name: qualified(t.Name)}
+		for _, attr := range t.Attr {
+			start.Attr = append(start.Attr, xml.Attr{Name: qualified(attr.Name), Value: attr.Value})
+		}
+		offset := int(d.InputOffset())
+		if strings.HasSuffix(raw[:offset], "/>") {
+			return start, "", nil
+		}
+		end := strings.LastIndex(raw, "</")
+		if end < offset {
+			return xml.StartElement{}, "", fmt.Errorf("element %s is not closed", start.Name.Local)
+		}
+		return start, raw[offset:end], nil
+	}
+}
+
+// child - the offsets in the content of an element of a child element
+type child struct {
+	name       xml.Name // name - as read by RawToken
+	start, end int
+}
+
+func childElements(inner string) ([]child, error) {
+	d := xml.NewDecoder(strings.NewReader(inner))
+	var children []child
+	depth := 0
+	for {
+		offset := int(d.InputOffset())
+		token, err := d.RawToken()
+		if err == io.EOF {
+			return children, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				children = append(children, child{name: t.Name, start: offset})
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+			if depth == 0 {
+				children[len(children)-1].end = int(d.InputOffset())
+			}
+		}
+	}
+}
+
+// declareInherited returns raw, the XML of the extension x, with declarations
+// of the namespace prefixes it uses without declaring added to its start tag.
+// Their namespaces are those of the names of x, whose XML was re-encoded with
+// the names translated. The default namespace is only declared when it
+// differs from space, the namespace of the parent element.
+func declareInherited(raw string, x *Extension, space string) (string, error) {
+	var names []xml.Name // names - of the elements and attributes, as read
+	var elements []bool  // elements - whether each name is of an element
+	var needed []string
+	var scopes []map[string]bool
+	declared := func(prefix string) bool {
+		for _, scope := range scopes {
+			if scope[prefix] {
+				return true
+			}
+		}
+		return prefix == "xml"
+	}
+	need := func(prefix string) {
+		if declared(prefix) {
+			return
+		}
+		for _, p := range needed {
+			if p == prefix {
+				return
+			}
+		}
+		needed = append(needed, prefix)
+	}
+	d := xml.NewDecoder(strings.NewReader(raw))
+	for {
+		token, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			scope := make(map[string]bool)
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" {
+					scope[attr.Name.Local] = true
+				} else if attr.Name.Space == "" && attr.Name.Local == "xmlns" {
+					scope[""] = true
+				}
+			}
+			scopes = append(scopes, scope)
+			need(t.Name.Space)
+			names, elements = append(names, t.Name), append(elements, true)
+			for _, attr := range t.Attr {
+				if !isNamespaceDecl(attr) {
+					if attr.Name.Space != "" {
+						need(attr.Name.Space)
+					}
+					names, elements = append(names, attr.Name), append(elements, false)
+				}
+			}
+		case xml.EndElement:
+			scopes = scopes[:len(scopes)-1]
+		}
+	}
+	if len(needed) == 0 {
+		return raw, nil
+	}
+
+	translated := []xml.Name{x.XMLName}
+	for _, attr := range x.Attrs {
+		translated = append(translated, attr.Name)
+	}
+	d = xml.NewDecoder(strings.NewReader(x.XML))
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if t, ok := token.(xml.StartElement); ok {
+			translated = append(translated, t.Name)
+			for _, attr := range t.Attr {
+				if !isNamespaceDecl(attr) {
+					translated = append(translated, attr.Name)
+				}
+			}
+		}
+	}
+	if len(translated) != len(names) {
+		return "", fmt.Errorf("cannot match the names of extension %s", x.XMLName.Local)
+	}
+
+	var decls bytes.Buffer
+	for _, prefix := range needed {
+		for i, name := range names {
+			// unprefixed attributes are in no namespace
+			if name.Space != prefix || (prefix == "" && !elements[i]) {
+				continue
+			}
+			namespace := translated[i].Space
+			if prefix == "" && namespace == space {
+				break
+			}
+			decls.WriteString(" xmlns")
+			if prefix != "" {
+				decls.WriteString(":" + prefix)
+			}
+			decls.WriteString(`="`)
+			xml.EscapeText(&decls, []byte(namespace))
+			decls.WriteString(`"`)
+			break
+		}
+	}
+	end := 1 + len(qualified(names[0]).Local)
+	return raw[:end] + decls.String() + raw[end:], nil
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package shared
 
 import (
+	"encoding/xml"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -35,3 +36,89 @@ func TestTimeParseReturnsCorrectValue(t *testing.T) {
 	assert.Equal(t, 0, int(dt.Second()))
 	assert.Equal(t, +1, zoneOffsetHours)
 }
+
+func TestExtensionKeepsAncestorNamespaces(t *testing.T) {
+	var doc struct {
+		Extension []Extension `xml:",any"`
+	}
+	err := xml.Unmarshal([]byte(`<doc xmlns:v="urn:v"><v:a v:b="c"><v:d>e</v:d></v:a></doc>`), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ext := doc.Extension[0]
+	assert.Equal(t, xml.Name{Space: "urn:v", Local: "a"}, ext.XMLName)
+	assert.Equal(t, Attrs{{Name: xml.Name{Space: "urn:v", Local: "b"}, Value: "c"}}, ext.Attrs)
+	assert.Equal(t, `<d xmlns="urn:v">e</d>`, ext.XML)
+}
+
+func TestExtensionMarshalRoundTrips(t *testing.T) {
+	ext := Extension{
+		XMLName: xml.Name{Space: "urn:v", Local: "a"},
+		Attrs:   Attrs{{Name: xml.Name{Local: "b"}, Value: "c"}},
+		XML:     `<d xmlns="urn:v">e</d>text`,
+	}
+	xmlData, err := xml.Marshal(ext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<a xmlns="urn:v" b="c"><d xmlns="urn:v">e</d>text</a>`, string(xmlData))
+	var decoded Extension
+	err = xml.Unmarshal(xmlData, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ext, decoded)
+}
+
+func TestAttrsSkipsNamespaceDeclarations(t *testing.T) {
+	var attrs Attrs
+	attrs.UnmarshalXMLAttr(xml.Attr{Name: xml.Name{Local: "xmlns"}, Value: "urn:a"})
+	attrs.UnmarshalXMLAttr(xml.Attr{Name: xml.Name{Space: "xmlns", Local: "v"}, Value: "urn:v"})
+	attrs.UnmarshalXMLAttr(xml.Attr{Name: xml.Name{Local: "id"}, Value: "1"})
+	assert.Equal(t, Attrs{{Name: xml.Name{Local: "id"}, Value: "1"}}, attrs)
+}
+
+// testElement - an element decoded and encoded with its extensions in place
+type testElement struct {
+	XMLName   xml.Name    `xml:"doc"`
+	A         string      `xml:"a"`
+	B         string      `xml:"b"`
+	Extension []Extension `xml:",any"`
+}
+
+func (t *testElement) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type Fields testElement
+	decoded := struct {
+		*Fields
+		Inner string `xml:",innerxml"`
+	}{Fields: (*Fields)(t)}
+	return DecodeElement(d, &start, &decoded, &decoded.Inner, &t.Extension)
+}
+
+func (t testElement) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type Fields testElement
+	encoded := Fields(t)
+	encoded.Extension = nil
+	start.Name = xml.Name{Local: "doc"}
+	return EncodeElement(e, start, encoded, t.Extension)
+}
+
+func TestDecodeElementKeepsRawExtensionsInPlace(t *testing.T) {
+	var doc testElement
+	err := xml.Unmarshal([]byte(`<doc xmlns:v="urn:v"><v:x v:y="1"><v:z/></v:x><a>1</a><w:x xmlns:w="urn:w">2</w:x><b>3</b></doc>`), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<v:x xmlns:v="urn:v" v:y="1"><v:z/></v:x>`, doc.Extension[0].Raw)
+	assert.Equal(t, 1, doc.Extension[0].Position)
+	assert.Equal(t, `<w:x xmlns:w="urn:w">2</w:x>`, doc.Extension[1].Raw)
+	assert.Equal(t, 2, doc.Extension[1].Position)
+
+	// extensions without Raw are written after the known elements
+	doc.Extension = append(doc.Extension, Extension{XMLName: xml.Name{Local: "c"}, XML: "4"})
+	xmlData, err := xml.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, `<doc><v:x xmlns:v="urn:v" v:y="1"><v:z/></v:x><a>1</a><w:x xmlns:w="urn:w">2</w:x><b>3</b><c>4</c></doc>`, string(xmlData))
+}
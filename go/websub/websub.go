@@ -0,0 +1,519 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package websub
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/cap/go/atom"
+)
+
+// RelHub is the link relation of the WebSub hubs of a feed
+const RelHub string = "hub"
+
+// MaxContentLength is the largest content distribution accepted from a hub
+const MaxContentLength int64 = 10 << 20
+
+// ErrNoHub is returned when a feed does not advertise a WebSub hub
+var ErrNoHub = errors.New("feed does not advertise a WebSub hub")
+
+// ErrUnsubscribed is returned by Wait when the subscription was unsubscribed
+var ErrUnsubscribed = errors.New("unsubscribed")
+
+// Discover returns the hubs and the topic advertised by the hub and self links
+// of the feed, ErrNoHub is returned if the feed has no hub or no self link
+func Discover(feed *atom.Feed) (hubs []string, topic string, err error) {
+	for _, link := range feed.Link {
+		switch link.Relation() {
+		case RelHub:
+			hubs = append(hubs, link.Href)
+		case atom.RelSelf:
+			if topic == "" {
+				topic = link.Href
+			}
+		}
+	}
+	if len(hubs) == 0 || topic == "" {
+		return nil, "", ErrNoHub
+	}
+	return hubs, topic, nil
+}
+
+// State - the state of a Subscription
+type State int
+
+// Subscription states
+const (
+	StatePending      State = iota // StatePending - the hub has not verified the subscription yet
+	StateActive                    // StateActive - the hub verified the subscription
+	StateDenied                    // StateDenied - the hub denied the subscription
+	StateUnsubscribed              // StateUnsubscribed - the hub verified the unsubscription
+	StateExpired                   // StateExpired - the lease of an active subscription ended without being renewed
+)
+
+func (s State) String() string {
+	switch s {
+	case StatePending:
+		return "pending"
+	case StateActive:
+		return "active"
+	case StateDenied:
+		return "denied"
+	case StateUnsubscribed:
+		return "unsubscribed"
+	case StateExpired:
+		return "expired"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// DeniedError - returned by Wait when the hub denied the subscription
+type DeniedError struct {
+	Topic  string
+	Reason string // Reason - the hub.reason given by the hub, may be empty
+}
+
+func (e *DeniedError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("subscription to %s denied", e.Topic)
+	}
+	return fmt.Sprintf("subscription to %s denied: %s", e.Topic, e.Reason)
+}
+
+// Subscription - a subscription of a Subscriber to a topic at a hub
+type Subscription struct {
+	ID       string // ID - identifies the subscription in its callback URL
+	Hub      string // Hub - the URL of the hub
+	Topic    string // Topic - the URL of the feed
+	Callback string // Callback - the URL the hub verifies and delivers to
+
+	secret string
+
+	mu            sync.Mutex
+	state         State
+	unsubscribing bool
+	expires       time.Time
+	reason        string
+	settled       chan struct{} // settled - closed once the hub verifies or denies the subscription
+}
+
+// State returns the state of the subscription
+func (s *Subscription) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state == StateActive && !s.expires.IsZero() && time.Now().After(s.expires) {
+		return StateExpired
+	}
+	return s.state
+}
+
+// Expires returns when the lease of an active subscription ends, it is zero
+// when the hub did not give a lease. The subscription must be renewed before
+// then, see Subscriber.Renew.
+func (s *Subscription) Expires() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.expires
+}
+
+// Wait blocks until the hub verifies or denies the subscription, returning
+// nil once it is active, a *DeniedError if it was denied or ctx's error
+func (s *Subscription) Wait(ctx context.Context) error {
+	select {
+	case <-s.settled:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch s.state {
+	case StateDenied:
+		return &DeniedError{Topic: s.Topic, Reason: s.reason}
+	case StateUnsubscribed:
+		return ErrUnsubscribed
+	}
+	return nil
+}
+
+// settle records the outcome of verification, callers hold s.mu
+func (s *Subscription) settle(state State) {
+	s.state = state
+	select {
+	case <-s.settled:
+	default:
+		close(s.settled)
+	}
+}
+
+// Subscriber - subscribes to feeds at WebSub hubs and receives the feeds they
+// push. The Subscriber is the http.Handler for the callbacks of its
+// subscriptions and must be served at CallbackURL.
+type Subscriber struct {
+	// Client - used to make requests to hubs, http.DefaultClient is used when nil
+	Client *http.Client
+	// CallbackURL - the public URL the Subscriber is served at, the callback of
+	// each subscription is CallbackURL followed by the subscription's ID
+	CallbackURL string
+	// LeaseSeconds - the lease requested from hubs, the hub decides when zero
+	LeaseSeconds int
+	// OnFeed - called with each feed delivered by a hub whose signature is
+	// valid, the hub waits for OnFeed to return
+	OnFeed func(sub *Subscription, feed *atom.Feed)
+
+	mu            sync.Mutex
+	subscriptions map[string]*Subscription
+}
+
+// SubscribeFeed subscribes to the feed at the first hub it advertises
+func (s *Subscriber) SubscribeFeed(ctx context.Context, feed *atom.Feed) (*Subscription, error) {
+	hubs, topic, err := Discover(feed)
+	if err != nil {
+		return nil, err
+	}
+	return s.Subscribe(ctx, hubs[0], topic)
+}
+
+// Subscribe requests a subscription to topic from hub. The subscription is
+// pending until the hub verifies it by calling back the Subscriber, see Wait.
+// Subscribing again to a topic at the same hub renews the existing
+// subscription, which is returned, rather than requesting another.
+func (s *Subscriber) Subscribe(ctx context.Context, hub string, topic string) (*Subscription, error) {
+	if sub := s.find(hub, topic); sub != nil {
+		if err := s.Renew(ctx, sub); err != nil {
+			return nil, err
+		}
+		return sub, nil
+	}
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	sub := &Subscription{
+		ID:       id,
+		Hub:      hub,
+		Topic:    topic,
+		Callback: strings.TrimSuffix(s.CallbackURL, "/") + "/" + id,
+		secret:   secret,
+		settled:  make(chan struct{}),
+	}
+	s.mu.Lock()
+	if s.subscriptions == nil {
+		s.subscriptions = make(map[string]*Subscription)
+	}
+	s.subscriptions[id] = sub
+	s.mu.Unlock()
+
+	if err := s.subscribe(ctx, sub); err != nil {
+		s.remove(sub)
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Renew requests the subscription again with the same callback, which the hub
+// treats as a renewal of its lease rather than another subscription. The
+// subscription stays active while the hub verifies the renewal.
+func (s *Subscriber) Renew(ctx context.Context, sub *Subscription) error {
+	sub.mu.Lock()
+	state, unsubscribing, reason := sub.state, sub.unsubscribing, sub.reason
+	sub.mu.Unlock()
+	switch {
+	case state == StateDenied:
+		return &DeniedError{Topic: sub.Topic, Reason: reason}
+	case state == StateUnsubscribed || unsubscribing:
+		return ErrUnsubscribed
+	}
+	return s.subscribe(ctx, sub)
+}
+
+// subscribe sends the subscription request of sub
+func (s *Subscriber) subscribe(ctx context.Context, sub *Subscription) error {
+	form := url.Values{
+		"hub.mode":     {"subscribe"},
+		"hub.topic":    {sub.Topic},
+		"hub.callback": {sub.Callback},
+		"hub.secret":   {sub.secret},
+	}
+	if s.LeaseSeconds > 0 {
+		form.Set("hub.lease_seconds", strconv.Itoa(s.LeaseSeconds))
+	}
+	return s.request(ctx, sub.Hub, form)
+}
+
+// find returns the subscription to topic at hub which is not being
+// unsubscribed, or nil
+func (s *Subscriber) find(hub string, topic string) *Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sub := range s.subscriptions {
+		sub.mu.Lock()
+		unsubscribing := sub.unsubscribing
+		sub.mu.Unlock()
+		if sub.Hub == hub && sub.Topic == topic && !unsubscribing {
+			return sub
+		}
+	}
+	return nil
+}
+
+// Unsubscribe asks the hub to end the subscription, which is unsubscribed
+// once the hub verifies the request. The subscription is kept when the hub
+// does not accept the request.
+func (s *Subscriber) Unsubscribe(ctx context.Context, sub *Subscription) error {
+	sub.mu.Lock()
+	sub.unsubscribing = true
+	sub.mu.Unlock()
+	form := url.Values{
+		"hub.mode":     {"unsubscribe"},
+		"hub.topic":    {sub.Topic},
+		"hub.callback": {sub.Callback},
+	}
+	if err := s.request(ctx, sub.Hub, form); err != nil {
+		sub.mu.Lock()
+		sub.unsubscribing = false
+		sub.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Subscriptions returns the subscriptions which have not been unsubscribed
+// and are not being unsubscribed
+func (s *Subscriber) Subscriptions() []*Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	subs := make([]*Subscription, 0, len(s.subscriptions))
+	for _, sub := range s.subscriptions {
+		sub.mu.Lock()
+		unsubscribing := sub.unsubscribing
+		sub.mu.Unlock()
+		if !unsubscribing {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+// request sends a subscription request to hub, which accepts it with a 202
+func (s *Subscriber) request(ctx context.Context, hub string, form url.Values) error {
+	req, err := http.NewRequest(http.MethodPost, hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("hub %s rejected %s request: HTTP status code: %d: %s", hub, form.Get("hub.mode"), resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// ServeHTTP handles the verification requests and content distributions of
+// hubs for the Subscriber's subscriptions
+func (s *Subscriber) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	s.mu.Lock()
+	sub := s.subscriptions[id]
+	s.mu.Unlock()
+	if sub == nil {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		s.verify(w, r, sub)
+	case http.MethodPost:
+		s.distribute(w, r, sub)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// verify answers the hub's verification of intent, echoing the challenge
+// only for a subscription or unsubscription the Subscriber requested
+func (s *Subscriber) verify(w http.ResponseWriter, r *http.Request, sub *Subscription) {
+	query := r.URL.Query()
+	if query.Get("hub.topic") != sub.Topic {
+		http.NotFound(w, r)
+		return
+	}
+	mode := query.Get("hub.mode")
+	switch mode {
+	case "subscribe", "unsubscribe", "denied":
+	default:
+		http.Error(w, "invalid hub.mode", http.StatusBadRequest)
+		return
+	}
+	// the subscription is removed without holding sub.mu, which is taken
+	// while holding s.mu
+	requested, ended := sub.verified(mode, query)
+	if ended {
+		s.remove(sub)
+	}
+	switch {
+	case !requested:
+		http.NotFound(w, r)
+	case mode == "denied":
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, query.Get("hub.challenge"))
+	}
+}
+
+// verified records the hub's verification of intent of mode, returning
+// whether it is for a request of the Subscriber and whether the subscription
+// ended
+func (s *Subscription) verified(mode string, query url.Values) (requested bool, ended bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch mode {
+	case "subscribe":
+		if s.unsubscribing {
+			return false, false
+		}
+		s.expires = time.Time{}
+		if lease, err := strconv.Atoi(query.Get("hub.lease_seconds")); err == nil && lease > 0 {
+			s.expires = time.Now().Add(time.Duration(lease) * time.Second)
+		}
+		s.settle(StateActive)
+		return true, false
+	case "unsubscribe":
+		if !s.unsubscribing {
+			return false, false
+		}
+		s.settle(StateUnsubscribed)
+		return true, true
+	case "denied":
+		s.reason = query.Get("hub.reason")
+		s.settle(StateDenied)
+		return true, true
+	}
+	return false, false
+}
+
+// distribute handles content pushed by the hub. Content without a valid
+// signature is acknowledged but ignored, as WebSub requires.
+func (s *Subscriber) distribute(w http.ResponseWriter, r *http.Request, sub *Subscription) {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, MaxContentLength+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if int64(len(body)) > MaxContentLength {
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if sub.State() != StateActive || !validSignature(r.Header.Get("X-Hub-Signature"), sub.secret, body) {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	var feed atom.Feed
+	if err := xml.Unmarshal(body, &feed); err != nil {
+		http.Error(w, fmt.Sprintf("invalid Atom feed: %v", err), http.StatusBadRequest)
+		return
+	}
+	feed.ResolveLinks(sub.Topic)
+	if s.OnFeed != nil {
+		s.OnFeed(sub, &feed)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// validSignature checks an X-Hub-Signature header of the form method=hex
+func validSignature(header string, secret string, body []byte) bool {
+	i := strings.Index(header, "=")
+	if i < 0 {
+		return false
+	}
+	newHash := signatureHash(header[:i])
+	if newHash == nil {
+		return false
+	}
+	signature, err := hex.DecodeString(header[i+1:])
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(signature, mac.Sum(nil))
+}
+
+// signatureHash returns the hash of an X-Hub-Signature method, or nil
+func signatureHash(method string) func() hash.Hash {
+	switch method {
+	case "sha1":
+		return sha1.New
+	case "sha256":
+		return sha256.New
+	case "sha384":
+		return sha512.New384
+	case "sha512":
+		return sha512.New
+	}
+	return nil
+}
+
+func (s *Subscriber) remove(sub *Subscription) {
+	s.mu.Lock()
+	delete(s.subscriptions, sub.ID)
+	s.mu.Unlock()
+}
+
+func (s *Subscriber) client() *http.Client {
+	if s.Client == nil {
+		return http.DefaultClient
+	}
+	return s.Client
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,377 @@
+/*
+Copyright 2018 The cap Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package websub
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/IBM/cap/go/atom"
+	"github.com/stretchr/testify/assert"
+)
+
+const testTopic = "https://alerts.example.com/feeds/tx.atom"
+
+const testPush = `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <id>https://alerts.example.com/feeds/tx.atom</id>
+  <title>Texas alerts</title>
+  <updated>2018-08-15T14:52:00-08:00</updated>
+  <link rel="self" href="tx.atom"/>
+  <entry>
+    <id>urn:test:1</id>
+    <title>Flash Flood Warning</title>
+    <updated>2018-08-15T14:52:00-08:00</updated>
+    <link href="../alerts/1.xml" type="application/cap+xml"/>
+  </entry>
+</feed>`
+
+// testHub - a WebSub hub stand-in which verifies every subscription request
+// and pushes content to the verified callbacks
+type testHub struct {
+	*httptest.Server
+	t      *testing.T
+	deny   string // deny - if set, subscriptions are denied with this reason
+	mu     sync.Mutex
+	secret map[string]string // secret - by callback of verified subscriptions
+}
+
+func newTestHub(t *testing.T) *testHub {
+	hub := &testHub{t: t, secret: make(map[string]string)}
+	hub.Server = httptest.NewServer(http.HandlerFunc(hub.serve))
+	return hub
+}
+
+func (h *testHub) serve(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	mode, callback, topic, secret := r.PostForm.Get("hub.mode"), r.PostForm.Get("hub.callback"), r.PostForm.Get("hub.topic"), r.PostForm.Get("hub.secret")
+	if topic != testTopic {
+		http.Error(w, "unknown topic", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	// verification of intent happens after the request is accepted
+	go func() {
+		query := url.Values{"hub.topic": {topic}}
+		if h.deny != "" {
+			query.Set("hub.mode", "denied")
+			query.Set("hub.reason", h.deny)
+			h.get(callback, query)
+			return
+		}
+		query.Set("hub.mode", mode)
+		query.Set("hub.challenge", "challenge-"+mode)
+		query.Set("hub.lease_seconds", "3600")
+		status, body := h.get(callback, query)
+		if status != http.StatusOK || body != "challenge-"+mode {
+			return
+		}
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if mode == "subscribe" {
+			h.secret[callback] = secret
+		} else {
+			delete(h.secret, callback)
+		}
+	}()
+}
+
+func (h *testHub) get(callback string, query url.Values) (int, string) {
+	resp, err := http.Get(callback + "?" + query.Encode())
+	if err != nil {
+		h.t.Error(err)
+		return 0, ""
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+// push distributes content to the callback, signed with secret when it is not empty
+func (h *testHub) push(callback string, secret string, content string) int {
+	req, err := http.NewRequest(http.MethodPost, callback, strings.NewReader(content))
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/atom+xml")
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(content))
+		req.Header.Set("X-Hub-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func (h *testHub) secretOf(callback string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.secret[callback]
+}
+
+// pushAll distributes content to every verified callback
+func (h *testHub) pushAll(content string) {
+	h.mu.Lock()
+	secrets := make(map[string]string, len(h.secret))
+	for callback, secret := range h.secret {
+		secrets[callback] = secret
+	}
+	h.mu.Unlock()
+	for callback, secret := range secrets {
+		h.push(callback, secret, content)
+	}
+}
+
+// newTestSubscriber returns a Subscriber served by a test server and the
+// feeds delivered to it
+func newTestSubscriber() (*Subscriber, *httptest.Server, chan *atom.Feed) {
+	feeds := make(chan *atom.Feed, 10)
+	subscriber := &Subscriber{LeaseSeconds: 3600, OnFeed: func(sub *Subscription, feed *atom.Feed) {
+		feeds <- feed
+	}}
+	server := httptest.NewServer(subscriber)
+	subscriber.CallbackURL = server.URL + "/websub/"
+	return subscriber, server, feeds
+}
+
+func testFeed(hub string) *atom.Feed {
+	return &atom.Feed{Link: []atom.Link{
+		{Href: "https://alerts.example.com/"},
+		{Href: testTopic, Rel: atom.RelSelf},
+		{Href: hub, Rel: RelHub},
+	}}
+}
+
+func waitActive(t *testing.T, sub *Subscription) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := sub.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	hubs, topic, err := Discover(testFeed("https://hub.example.com/"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"https://hub.example.com/"}, hubs)
+	assert.Equal(t, testTopic, topic)
+
+	_, _, err = Discover(&atom.Feed{Link: []atom.Link{{Href: testTopic, Rel: atom.RelSelf}}})
+	assert.Equal(t, ErrNoHub, err)
+}
+
+func TestSubscriberReceivesSignedContent(t *testing.T) {
+	hub := newTestHub(t)
+	defer hub.Close()
+	subscriber, server, feeds := newTestSubscriber()
+	defer server.Close()
+
+	sub, err := subscriber.SubscribeFeed(context.Background(), testFeed(hub.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, strings.HasPrefix(sub.Callback, server.URL+"/websub/"))
+	waitActive(t, sub)
+	assert.Equal(t, StateActive, sub.State())
+	assert.WithinDuration(t, time.Now().Add(time.Hour), sub.Expires(), time.Minute)
+
+	// wait for the hub to record the subscription after the challenge is echoed
+	for hub.secretOf(sub.Callback) == "" {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, http.StatusAccepted, hub.push(sub.Callback, hub.secretOf(sub.Callback), testPush))
+	feed := <-feeds
+	assert.Equal(t, "Texas alerts", feed.Title.Content)
+	alertURL, err := feed.Entries[0].AlertURL()
+	assert.Nil(t, err)
+	assert.Equal(t, "https://alerts.example.com/alerts/1.xml", alertURL)
+
+	// unsigned and wrongly signed content is acknowledged but ignored
+	assert.Equal(t, http.StatusAccepted, hub.push(sub.Callback, "", testPush))
+	assert.Equal(t, http.StatusAccepted, hub.push(sub.Callback, "wrong secret", testPush))
+	assert.Equal(t, http.StatusBadRequest, hub.push(sub.Callback, hub.secretOf(sub.Callback), "not a feed"))
+	assert.Equal(t, 0, len(feeds))
+
+	if err := subscriber.Unsubscribe(context.Background(), sub); err != nil {
+		t.Fatal(err)
+	}
+	for sub.State() != StateUnsubscribed {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, 0, len(subscriber.Subscriptions()))
+	assert.Equal(t, http.StatusNotFound, hub.push(sub.Callback, "", testPush))
+}
+
+func TestSubscribeAgainRenewsSubscription(t *testing.T) {
+	hub := newTestHub(t)
+	defer hub.Close()
+	subscriber, server, feeds := newTestSubscriber()
+	defer server.Close()
+
+	sub, err := subscriber.Subscribe(context.Background(), hub.URL, testTopic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitActive(t, sub)
+	for hub.secretOf(sub.Callback) == "" {
+		time.Sleep(time.Millisecond)
+	}
+	secret := hub.secretOf(sub.Callback)
+
+	// the lease has ended, the renewal verified by the hub extends it
+	sub.mu.Lock()
+	sub.expires = time.Now().Add(-time.Second)
+	sub.mu.Unlock()
+	assert.Equal(t, StateExpired, sub.State())
+	assert.Equal(t, http.StatusAccepted, hub.push(sub.Callback, secret, testPush))
+	assert.Equal(t, 0, len(feeds))
+
+	again, err := subscriber.Subscribe(context.Background(), hub.URL, testTopic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, sub == again)
+	assert.Equal(t, 1, len(subscriber.Subscriptions()))
+	for sub.State() != StateActive {
+		time.Sleep(time.Millisecond)
+	}
+	assert.WithinDuration(t, time.Now().Add(time.Hour), sub.Expires(), time.Minute)
+
+	hub.pushAll(testPush)
+	<-feeds
+	assert.Equal(t, 0, len(feeds))
+	hub.mu.Lock()
+	assert.Equal(t, map[string]string{sub.Callback: secret}, hub.secret)
+	hub.mu.Unlock()
+}
+
+func TestRenewFailsOnceUnsubscribed(t *testing.T) {
+	hub := newTestHub(t)
+	defer hub.Close()
+	subscriber, server, _ := newTestSubscriber()
+	defer server.Close()
+
+	sub, err := subscriber.Subscribe(context.Background(), hub.URL, testTopic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitActive(t, sub)
+	if err := subscriber.Unsubscribe(context.Background(), sub); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ErrUnsubscribed, subscriber.Renew(context.Background(), sub))
+	// the pending unsubscription is not listed
+	assert.Equal(t, 0, len(subscriber.Subscriptions()))
+}
+
+func TestUnsubscribeKeepsSubscriptionWhenRequestFails(t *testing.T) {
+	hub := newTestHub(t)
+	subscriber, server, _ := newTestSubscriber()
+	defer server.Close()
+
+	sub, err := subscriber.Subscribe(context.Background(), hub.URL, testTopic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitActive(t, sub)
+	hub.Close()
+	assert.NotNil(t, subscriber.Unsubscribe(context.Background(), sub))
+	assert.Equal(t, []*Subscription{sub}, subscriber.Subscriptions())
+	assert.NotEqual(t, ErrUnsubscribed, subscriber.Renew(context.Background(), sub))
+}
+
+func TestSubscriberReportsDenial(t *testing.T) {
+	hub := newTestHub(t)
+	hub.deny = "topic requires registration"
+	defer hub.Close()
+	subscriber, server, _ := newTestSubscriber()
+	defer server.Close()
+
+	sub, err := subscriber.Subscribe(context.Background(), hub.URL, testTopic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = sub.Wait(ctx)
+	assert.Equal(t, "subscription to "+testTopic+" denied: topic requires registration", err.Error())
+	assert.Equal(t, StateDenied, sub.State())
+}
+
+func TestSubscriberReturnsErrWhenHubRejectsRequest(t *testing.T) {
+	hub := newTestHub(t)
+	defer hub.Close()
+	subscriber, server, _ := newTestSubscriber()
+	defer server.Close()
+
+	_, err := subscriber.Subscribe(context.Background(), hub.URL, "https://alerts.example.com/unknown")
+	assert.Equal(t, "hub "+hub.URL+" rejected subscribe request: HTTP status code: 404: unknown topic", err.Error())
+	assert.Equal(t, 0, len(subscriber.Subscriptions()))
+}
+
+func TestSubscriberRejectsUnexpectedVerification(t *testing.T) {
+	hub := newTestHub(t)
+	defer hub.Close()
+	subscriber, server, _ := newTestSubscriber()
+	defer server.Close()
+	sub, err := subscriber.Subscribe(context.Background(), hub.URL, testTopic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitActive(t, sub)
+
+	for _, query := range []url.Values{
+		{"hub.mode": {"subscribe"}, "hub.topic": {"https://alerts.example.com/other"}, "hub.challenge": {"x"}},
+		{"hub.mode": {"unsubscribe"}, "hub.topic": {testTopic}, "hub.challenge": {"x"}},
+	} {
+		status, _ := hub.get(sub.Callback, query)
+		assert.Equal(t, http.StatusNotFound, status)
+	}
+	status, _ := hub.get(server.URL+"/websub/unknown", url.Values{"hub.mode": {"subscribe"}, "hub.topic": {testTopic}})
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, StateActive, sub.State())
+}
+
+func TestValidSignature(t *testing.T) {
+	body := []byte(testPush)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	signature := hex.EncodeToString(mac.Sum(nil))
+	assert.True(t, validSignature("sha256="+signature, "secret", body))
+	assert.False(t, validSignature("sha256="+signature, "other", body))
+	assert.False(t, validSignature("md5="+signature, "secret", body))
+	assert.False(t, validSignature(signature, "secret", body))
+	assert.False(t, validSignature("sha256="+signature, "secret", bytes.ToUpper(body)))
+}